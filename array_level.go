@@ -0,0 +1,131 @@
+package neatly
+
+import (
+	"github.com/viant/toolbox"
+	"strings"
+)
+
+//arrayLevels tracks inline array element indexes of a field for each array nesting level across continuation rows,
+//only columns sharing the field array paths are considered, so that independent inline arrays do not affect each other
+type arrayLevels struct {
+	depths  map[string]int  //array depth of each related column
+	others  map[string]bool //array columns unrelated to the field
+	indexes []int           //current element index of each level
+	present []bool          //flag indicating that current element of each level has been started
+}
+
+//Next advances indexes for supplied continuation row, it returns the row array level or 0 if the row has values only in unrelated arrays
+func (l *arrayLevels) Next(record *toolbox.DelimitedRecord) int {
+	var rowDepths = l.rowDepths(record)
+	var level = 1
+	if len(rowDepths) > 0 {
+		level = len(l.indexes)
+		for depth := range rowDepths {
+			if depth < level {
+				level = depth
+			}
+		}
+	} else if l.hasOtherValues(record) {
+		return 0
+	}
+	if l.present[level-1] {
+		l.indexes[level-1]++
+	}
+	l.present[level-1] = true
+	for i := level; i < len(l.indexes); i++ {
+		l.indexes[i] = 0
+		l.present[i] = rowDepths[i+1]
+	}
+	return level
+}
+
+//Indexes returns element indexes for supplied array depth
+func (l *arrayLevels) Indexes(depth int) []int {
+	return l.indexes[:depth]
+}
+
+//rowDepths returns array depths of the related columns with non-empty values
+func (l *arrayLevels) rowDepths(record *toolbox.DelimitedRecord) map[int]bool {
+	var result = make(map[int]bool)
+	for column, depth := range l.depths {
+		if hasValue(record, column) {
+			result[depth] = true
+		}
+	}
+	return result
+}
+
+//hasOtherValues returns true if any unrelated array column has non-empty value
+func (l *arrayLevels) hasOtherValues(record *toolbox.DelimitedRecord) bool {
+	for column := range l.others {
+		if hasValue(record, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasValue(record *toolbox.DelimitedRecord, column string) bool {
+	value := toolbox.AsString(record.Record[column])
+	return value != "" && value != "<nil>"
+}
+
+//arrayPaths returns path of each array component of the field, i.e. Requests and Requests.Headers for []Requests.[]Headers.Name
+func arrayPaths(field *Field) []string {
+	var result = make([]string, 0)
+	var path = make([]string, 0)
+	for ; field != nil; field = field.Child {
+		path = append(path, field.Field)
+		if field.IsArray {
+			result = append(result, strings.Join(path, "."))
+		}
+	}
+	return result
+}
+
+//isArrayPathPrefix returns true if all prefix paths are the leading paths of the candidate
+func isArrayPathPrefix(prefix, candidate []string) bool {
+	if len(prefix) > len(candidate) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != candidate[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//newArrayLevels creates field array levels for supplied main record, a column is related to the field if its array paths
+//are the leading field array paths or the other way around
+func newArrayLevels(record *toolbox.DelimitedRecord, target *Field) *arrayLevels {
+	var result = &arrayLevels{depths: make(map[string]int), others: make(map[string]bool)}
+	var targetPaths = arrayPaths(target)
+	var maxDepth = 1
+	for _, column := range record.Columns[1:] {
+		if column == "" || isExtendsColumn(column) {
+			continue
+		}
+		field := NewField(column)
+		if field.IsRoot || field.IsVirtual || !field.HasArrayComponent {
+			continue
+		}
+		paths := arrayPaths(field)
+		if !isArrayPathPrefix(paths, targetPaths) && !isArrayPathPrefix(targetPaths, paths) {
+			result.others[column] = true
+			continue
+		}
+		depth := len(paths)
+		result.depths[column] = depth
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	result.indexes = make([]int, maxDepth)
+	result.present = make([]bool, maxDepth)
+	var rowDepths = result.rowDepths(record)
+	for i := range result.present {
+		result.present[i] = rowDepths[i+1]
+	}
+	return result
+}
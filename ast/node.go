@@ -0,0 +1,159 @@
+package ast
+
+//Position represents node position in a neatly document, line and column are 1 based
+type Position struct {
+	Line   int
+	Column int
+}
+
+//Pos returns node position
+func (p Position) Pos() Position {
+	return p
+}
+
+//Node represents neatly document node
+type Node interface {
+	Pos() Position
+}
+
+//Document represents parsed neatly document
+type Document struct {
+	Position
+	URL   string
+	Nodes []Node
+}
+
+//Import represents import directive line, i.e. Import,common.csv as c
+type Import struct {
+	Position
+	Raw   string
+	Specs []string //import specs: URI with optional alias
+}
+
+//Comment represents comment line starting with //
+type Comment struct {
+	Position
+	Text string
+}
+
+//Blank represents empty line
+type Blank struct {
+	Position
+}
+
+//Template represents tag template declaration with its rows
+type Template struct {
+	Position
+	Raw         string
+	Declaration string //template declaration, i.e. Template HttpCheck(url, code=200)
+	Fields      []*HeaderField
+	Rows        []Node //template data and continuation rows
+}
+
+//Iterator represents tag iterator, i.e. {1..003}
+type Iterator struct {
+	Position
+	Min   int
+	Max   int
+	Width int //max value width, i.e. 3 for {1..003}
+	Raw   string
+}
+
+//TagHeader represents tag header line
+type TagHeader struct {
+	Position
+	Raw        string
+	Tag        string    //tag column value, i.e. {Id}[]Users<common.Users
+	Name       string    //tag name
+	IsArray    bool      //flag indicating array tag
+	Dimensions int       //number of array dimensions
+	KeyField   string    //map keyed tag key field
+	Import     string    //imported tag expression
+	Iterator   *Iterator //optional tag iterator
+	Fields     []*HeaderField
+}
+
+//HasArrayFields returns true if any object field has an array component, in that case rows following data row are continuation rows
+func (h *TagHeader) HasArrayFields() bool {
+	for _, field := range h.Fields {
+		if field.HasArrayComponent && !field.IsVirtual && !field.IsRoot {
+			return true
+		}
+	}
+	return false
+}
+
+//HeaderField represents tag header field column
+type HeaderField struct {
+	Position
+	Expression        string
+	Name              string //expression without root and virtual prefixes
+	IsRoot            bool   //field starts with /
+	IsVirtual         bool   //field starts with : or lower case letter
+	HasArrayComponent bool   //field contains []
+}
+
+//Terminator represents array row terminator, it starts a new data row within array values
+type Terminator struct {
+	Position
+}
+
+//DataRow represents data row, it creates a new tag object
+type DataRow struct {
+	Position
+	Raw        string
+	Terminator *Terminator //optional array row terminator
+	Columns    int         //number of row columns
+	Cells      []*Cell
+}
+
+//ContinuationRow represents row extending array fields of the preceding data row
+type ContinuationRow struct {
+	Position
+	Raw     string
+	Columns int //number of row columns
+	Cells   []*Cell
+}
+
+//CellKind represents cell value kind
+type CellKind int
+
+const (
+	//TextCell represents text or JSON value
+	TextCell CellKind = iota
+	//EscapeCell represents escaped special character value, i.e. $$text
+	EscapeCell
+	//ReferenceCell represents forward tag reference, i.e. %Users
+	ReferenceCell
+	//AssetCell represents external resource, i.e. @request.json
+	AssetCell
+	//VariableCell represents variable or udf expression, i.e. $var or $Udf(arg)
+	VariableCell
+	//TemplateCell represents tag template call, i.e. $Use(HttpCheck, /)
+	TemplateCell
+	//ExpressionCell represents computed value expression, i.e. $(port + 1)
+	ExpressionCell
+)
+
+var cellKindNames = map[CellKind]string{
+	TextCell:       "text",
+	EscapeCell:     "escape",
+	ReferenceCell:  "reference",
+	AssetCell:      "asset",
+	VariableCell:   "variable",
+	TemplateCell:   "template",
+	ExpressionCell: "expression",
+}
+
+//String returns cell kind name
+func (k CellKind) String() string {
+	return cellKindNames[k]
+}
+
+//Cell represents non empty row cell
+type Cell struct {
+	Position
+	Field string //header field expression
+	Value string
+	Kind  CellKind
+}
@@ -0,0 +1,365 @@
+package ast
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	arrayRowTerminator = "-"
+	importDirective    = "Import"
+	templateDirective  = "Template "
+	templateCallPrefix = "$Use("
+	expressionPrefix   = "$("
+	heredocPrefix      = "<<"
+	commentPrefix      = "//"
+)
+
+//Line represents logical document line, lines of quoted multi-line cells and heredoc blocks are joined into one line
+type Line struct {
+	Number int //source line number of the first line
+	Text   string
+}
+
+//ReadLines reads logical document lines, it joins lines of quoted multi-line cells and heredoc blocks into one line
+func ReadLines(scanner *bufio.Scanner) ([]*Line, error) {
+	var lines = make([]*Line, 0)
+	var lineNumber = 0
+	var pending = ""
+	var pendingLineNumber = 0
+	for scanner.Scan() {
+		var line = scanner.Text()
+		lineNumber++
+		if pending != "" {
+			line = pending + "\n" + line
+		} else {
+			pendingLineNumber = lineNumber
+			if strings.HasPrefix(line, commentPrefix) {
+				lines = append(lines, &Line{Number: lineNumber, Text: line})
+				continue
+			}
+		}
+		if hasOpenQuotedCell(line) {
+			pending = line
+			continue
+		}
+		if token, has := heredocToken(line); has {
+			block, consumed, err := readHeredoc(scanner, token)
+			lineNumber += consumed
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", pendingLineNumber, err)
+			}
+			line = string(line[:len(line)-len(heredocPrefix+token)]) + block
+			if _, has = heredocToken(line); has || hasOpenQuotedCell(line) {
+				pending = line
+				continue
+			}
+		}
+		pending = ""
+		lines = append(lines, &Line{Number: pendingLineNumber, Text: line})
+	}
+	if pending != "" {
+		return nil, fmt.Errorf("line %v: unterminated quoted cell", pendingLineNumber)
+	}
+	return lines, nil
+}
+
+//hasOpenQuotedCell returns true if the last line cell starts with a quote that is not closed, thus the cell continues on the next line,
+//quotes inside unquoted cells i.e. 5" screen are literal
+func hasOpenQuotedCell(line string) bool {
+	var cellStart = true
+	for i := 0; i < len(line); i++ {
+		switch {
+		case cellStart && line[i] == '"':
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] != '"' {
+					continue
+				}
+				if i+1 < len(line) && line[i+1] == '"' {
+					i++
+					continue
+				}
+				closed = true
+				break
+			}
+			if !closed {
+				return true
+			}
+			cellStart = false
+		case line[i] == ',':
+			cellStart = true
+		default:
+			cellStart = false
+		}
+	}
+	return false
+}
+
+//heredocToken returns heredoc terminator token if line ends with <<TOKEN cell
+func heredocToken(line string) (string, bool) {
+	index := strings.LastIndex(line, heredocPrefix)
+	if index == -1 || (index > 0 && line[index-1] != ',') {
+		return "", false
+	}
+	token := line[index+len(heredocPrefix):]
+	if token == "" {
+		return "", false
+	}
+	for _, r := range token {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return "", false
+		}
+	}
+	return token, true
+}
+
+//readHeredoc reads heredoc block until terminator token line, it returns quoted block followed by the rest of the terminator line
+func readHeredoc(scanner *bufio.Scanner, token string) (string, int, error) {
+	var block = make([]string, 0)
+	var consumed = 0
+	for scanner.Scan() {
+		consumed++
+		line := scanner.Text()
+		if line == token || strings.HasPrefix(line, token+",") {
+			text := strings.Join(block, "\n")
+			return `"` + strings.Replace(text, `"`, `""`, -1) + `"` + line[len(token):], consumed, nil
+		}
+		block = append(block, line)
+	}
+	return "", consumed, fmt.Errorf("unterminated heredoc: %v", token)
+}
+
+//rowState tracks whether the following row continues array fields of the preceding data row
+type rowState struct {
+	fields     map[int]*HeaderField
+	arrayField bool
+	continued  bool
+}
+
+func newRowState(fields []*HeaderField, arrayFields bool) *rowState {
+	var result = &rowState{fields: make(map[int]*HeaderField), arrayField: arrayFields}
+	for _, field := range fields {
+		result.fields[field.Column] = field
+	}
+	return result
+}
+
+//row returns data or continuation row node for supplied line
+func (s *rowState) row(line *Line) Node {
+	var text = line.Text
+	var terminator *Terminator
+	if strings.HasPrefix(text, arrayRowTerminator) {
+		terminator = &Terminator{Position: Position{Line: line.Number, Column: 1}}
+		text = strings.Replace(text, arrayRowTerminator, "", 1)
+	}
+	columns, cells := s.cells(line.Number, text)
+	if terminator == nil && s.continued {
+		if len(cells) > 0 {
+			return &ContinuationRow{Position: Position{Line: line.Number, Column: 1}, Raw: line.Text, Columns: columns, Cells: cells}
+		}
+		s.continued = false
+		return &DataRow{Position: Position{Line: line.Number, Column: 1}, Raw: line.Text, Columns: columns, Cells: cells}
+	}
+	s.continued = s.arrayField && len(cells) > 0
+	return &DataRow{Position: Position{Line: line.Number, Column: 1}, Raw: line.Text, Terminator: terminator, Columns: columns, Cells: cells}
+}
+
+//cells returns number of row columns and non empty row cells
+func (s *rowState) cells(lineNumber int, text string) (int, []*Cell) {
+	var result = make([]*Cell, 0)
+	columns := splitColumns(text)
+	for i, value := range columns {
+		if i == 0 || value == "" {
+			continue
+		}
+		var cell = &Cell{Position: Position{Line: lineNumber, Column: i + 1}, Value: value, Kind: cellKind(value)}
+		if field, ok := s.fields[i+1]; ok {
+			cell.Field = field.Expression
+		}
+		result = append(result, cell)
+	}
+	return len(columns), result
+}
+
+//Parse parses neatly document text into document nodes
+func Parse(URL string, text string) (*Document, error) {
+	text = strings.Replace(text, "\r", "", len(text))
+	lines, err := ReadLines(bufio.NewScanner(strings.NewReader(text)))
+	if err != nil {
+		return nil, err
+	}
+	var document = &Document{Position: Position{Line: 1, Column: 1}, URL: URL, Nodes: make([]Node, 0)}
+	var header *TagHeader
+	var template *Template
+	var state *rowState
+	for _, line := range lines {
+		var position = Position{Line: line.Number, Column: 1}
+		var isRow = strings.HasPrefix(line.Text, ",") || strings.HasPrefix(line.Text, arrayRowTerminator)
+		switch {
+		case strings.HasPrefix(line.Text, commentPrefix):
+			document.Nodes = append(document.Nodes, &Comment{Position: position, Text: strings.TrimSpace(line.Text[len(commentPrefix):])})
+		case strings.TrimSpace(line.Text) == "":
+			document.Nodes = append(document.Nodes, &Blank{Position: position})
+		case template != nil && isRow:
+			template.Rows = append(template.Rows, state.row(line))
+		case isRow:
+			if state == nil {
+				state = newRowState(nil, false)
+			}
+			document.Nodes = append(document.Nodes, state.row(line))
+		case header == nil && strings.HasPrefix(line.Text, importDirective+","):
+			columns := splitColumns(line.Text)
+			var node = &Import{Position: position, Raw: line.Text, Specs: make([]string, 0)}
+			for _, spec := range columns[1:] {
+				if spec = strings.TrimSpace(spec); spec != "" {
+					node.Specs = append(node.Specs, spec)
+				}
+			}
+			document.Nodes = append(document.Nodes, node)
+			template = nil
+		case strings.HasPrefix(line.Text, templateDirective) || strings.HasPrefix(line.Text, `"`+templateDirective):
+			columns := splitColumns(line.Text)
+			template = &Template{Position: position, Raw: line.Text, Declaration: columns[0], Fields: headerFields(line.Number, columns), Rows: make([]Node, 0)}
+			state = newRowState(template.Fields, hasArrayFields(template.Fields))
+			document.Nodes = append(document.Nodes, template)
+		default:
+			header = parseTagHeader(line)
+			template = nil
+			state = newRowState(header.Fields, header.HasArrayFields())
+			document.Nodes = append(document.Nodes, header)
+		}
+	}
+	return document, nil
+}
+
+func parseTagHeader(line *Line) *TagHeader {
+	columns := splitColumns(line.Text)
+	var result = ParseTag(columns[0])
+	result.Position = Position{Line: line.Number, Column: 1}
+	result.Raw = line.Text
+	result.Fields = headerFields(line.Number, columns)
+	if result.Iterator != nil {
+		result.Iterator.Position = result.Position
+	}
+	return result
+}
+
+//ParseTag parses tag column value i.e. {Id}[]Users{1..3}<common.Users
+func ParseTag(key string) *TagHeader {
+	var result = &TagHeader{Tag: key}
+	if importIndex := strings.Index(key, "<"); importIndex != -1 {
+		result.Import = strings.TrimSpace(string(key[importIndex+1:]))
+		key = strings.TrimSpace(string(key[:importIndex]))
+	}
+	if strings.HasPrefix(key, "{") {
+		if keyEndPosition := strings.Index(key, "}"); keyEndPosition != -1 {
+			result.KeyField = strings.TrimSpace(key[1:keyEndPosition])
+			key = string(key[keyEndPosition+1:])
+		}
+	}
+	if start := strings.Index(key, "{"); start != -1 {
+		if end := strings.Index(key, "}"); end > start {
+			pair := strings.Split(key[start+1:end], "..")
+			if len(pair) == 2 {
+				min, _ := strconv.Atoi(strings.TrimSpace(pair[0]))
+				max, _ := strconv.Atoi(strings.TrimSpace(pair[1]))
+				result.Iterator = &Iterator{Min: min, Max: max, Width: len(strings.TrimSpace(pair[1])), Raw: key[start : end+1]}
+				key = string(key[:start])
+			}
+		}
+	}
+	for len(key) > 2 && string(key[0:2]) == "[]" {
+		key = string(key[2:])
+		result.IsArray = true
+		result.Dimensions++
+	}
+	if rangeIndex := strings.LastIndex(key, "{"); rangeIndex != -1 {
+		key = string(key[:rangeIndex])
+	}
+	result.Name = key
+	return result
+}
+
+//headerFields returns non empty header field columns
+func headerFields(lineNumber int, columns []string) []*HeaderField {
+	var result = make([]*HeaderField, 0)
+	for i, expression := range columns {
+		if i == 0 || expression == "" {
+			continue
+		}
+		field := ParseField(expression)
+		field.Position = Position{Line: lineNumber, Column: i + 1}
+		result = append(result, field)
+	}
+	return result
+}
+
+//ParseField parses header field expression flags, i.e. /:[]Items
+func ParseField(expression string) *HeaderField {
+	var result = &HeaderField{Expression: expression}
+	var name = expression
+	if strings.HasPrefix(name, "/") {
+		result.IsRoot = true
+		name = string(name[1:])
+	}
+	if strings.HasPrefix(name, ":") {
+		result.IsVirtual = true
+		name = string(name[1:])
+	}
+	result.Name = name
+	result.HasArrayComponent = strings.Contains(name, "[]")
+	name = strings.TrimLeft(name, "[]")
+	if runes := []rune(name); len(runes) > 0 && unicode.IsLower(runes[0]) {
+		result.IsVirtual = true
+	}
+	return result
+}
+
+func hasArrayFields(fields []*HeaderField) bool {
+	return (&TagHeader{Fields: fields}).HasArrayFields()
+}
+
+//splitColumns splits CSV line into columns, malformed CSV line is split by comma
+func splitColumns(text string) []string {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	columns, err := reader.Read()
+	if err != nil || len(columns) == 0 {
+		return strings.Split(text, ",")
+	}
+	return columns
+}
+
+//cellKind returns cell value kind
+func cellKind(value string) CellKind {
+	text := strings.TrimSpace(value)
+	if len(text) >= 2 {
+		switch string(text[:2]) {
+		case "$$", "@@", "##", "%%", "[!", "{!":
+			return EscapeCell
+		}
+		switch string(text[len(text)-2:]) {
+		case "!]", "!}":
+			return EscapeCell
+		}
+	}
+	switch {
+	case strings.HasPrefix(text, "%"):
+		return ReferenceCell
+	case strings.HasPrefix(text, templateCallPrefix):
+		return TemplateCell
+	case strings.HasPrefix(text, expressionPrefix):
+		return ExpressionCell
+	case strings.HasPrefix(text, "@"), strings.HasPrefix(text, "#"):
+		return AssetCell
+	case strings.HasPrefix(text, "$"):
+		return VariableCell
+	}
+	return TextCell
+}
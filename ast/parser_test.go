@@ -0,0 +1,120 @@
+package ast
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `Import,common.csv as c
+Root,Name,Users,Request,:v
+// users
+
+,app,%Users,@request.json,$$x
+Template Check(url)
+,$url
+{Id}[]Users{1..03}<c.Users,Id,[]Tags,$Use(Check, /)
+,1,a,$v
+,,b
+-,2,"multi
+line"
+`
+	document, err := Parse("mem://doc.csv", text)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Equal(t, 10, len(document.Nodes)) {
+		return
+	}
+	importNode, ok := document.Nodes[0].(*Import)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, []string{"common.csv as c"}, importNode.Specs)
+	}
+	root, ok := document.Nodes[1].(*TagHeader)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, "Root", root.Name)
+		assert.EqualValues(t, 4, len(root.Fields))
+		assert.True(t, root.Fields[3].IsVirtual)
+		assert.EqualValues(t, Position{Line: 2, Column: 5}, root.Fields[3].Pos())
+		assert.False(t, root.HasArrayFields())
+	}
+	comment, ok := document.Nodes[2].(*Comment)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, "users", comment.Text)
+	}
+	_, ok = document.Nodes[3].(*Blank)
+	assert.True(t, ok)
+	row, ok := document.Nodes[4].(*DataRow)
+	if assert.True(t, ok) && assert.Equal(t, 4, len(row.Cells)) {
+		var kinds = []CellKind{TextCell, ReferenceCell, AssetCell, EscapeCell}
+		for i, cell := range row.Cells {
+			assert.EqualValues(t, kinds[i], cell.Kind, cell.Value)
+		}
+		assert.EqualValues(t, "Request", row.Cells[2].Field)
+		assert.EqualValues(t, Position{Line: 5, Column: 4}, row.Cells[2].Pos())
+		assert.EqualValues(t, 5, row.Columns)
+	}
+	template, ok := document.Nodes[5].(*Template)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, "Template Check(url)", template.Declaration)
+		assert.Equal(t, 1, len(template.Rows))
+	}
+	users, ok := document.Nodes[6].(*TagHeader)
+	if !assert.True(t, ok, "%T", document.Nodes[6]) {
+		return
+	}
+	assert.EqualValues(t, "Users", users.Name)
+	assert.EqualValues(t, "Id", users.KeyField)
+	assert.EqualValues(t, "c.Users", users.Import)
+	assert.True(t, users.IsArray)
+	assert.True(t, users.HasArrayFields())
+	if assert.NotNil(t, users.Iterator) {
+		assert.EqualValues(t, 1, users.Iterator.Min)
+		assert.EqualValues(t, 3, users.Iterator.Max)
+		assert.EqualValues(t, 2, users.Iterator.Width)
+	}
+	_, ok = document.Nodes[7].(*DataRow)
+	assert.True(t, ok)
+	continuation, ok := document.Nodes[8].(*ContinuationRow)
+	if assert.True(t, ok) {
+		assert.EqualValues(t, "b", continuation.Cells[0].Value)
+	}
+	terminated, ok := document.Nodes[9].(*DataRow)
+	if assert.True(t, ok) {
+		assert.NotNil(t, terminated.Terminator)
+		assert.EqualValues(t, 11, terminated.Line)
+		assert.EqualValues(t, "multi\nline", terminated.Cells[1].Value)
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tag := ParseTag("[][]Cells{1 .. 003}")
+	assert.EqualValues(t, "Cells", tag.Name)
+	assert.EqualValues(t, 2, tag.Dimensions)
+	if assert.NotNil(t, tag.Iterator) {
+		assert.EqualValues(t, 3, tag.Iterator.Width)
+	}
+	assert.EqualValues(t, "Users", ParseTag("Users{x").Name)
+}
+
+func TestParseField(t *testing.T) {
+	field := ParseField("/:[]Items")
+	assert.True(t, field.IsRoot)
+	assert.True(t, field.IsVirtual)
+	assert.True(t, field.HasArrayComponent)
+	assert.EqualValues(t, "[]Items", field.Name)
+	assert.True(t, ParseField("[]items").IsVirtual)
+	assert.False(t, ParseField("Items=abc").IsVirtual)
+}
+
+func TestParse_Error(t *testing.T) {
+	document, err := Parse("mem://doc.csv", "Root,Name,Size\n,O\"Brien,5\" screen\n,\"a,\"\"b\"\"\",\"c\nd\"\n")
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(document.Nodes)) {
+		assert.EqualValues(t, ",O\"Brien,5\" screen", document.Nodes[1].(*DataRow).Raw)
+		assert.EqualValues(t, ",\"a,\"\"b\"\"\",\"c\nd\"", document.Nodes[2].(*DataRow).Raw)
+	}
+	_, err = Parse("mem://doc.csv", "Root,Name\n,\"abc\n")
+	assert.EqualValues(t, "line 2: unterminated quoted cell", err.Error())
+	_, err = Parse("mem://doc.csv", "Root,Name\n,<<EOF\nabc\n")
+	assert.EqualValues(t, "line 2: unterminated heredoc: EOF", err.Error())
+}
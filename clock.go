@@ -0,0 +1,124 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox/data"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//Clock represents time source consulted by time dependent udfs
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+//SystemClock returns wall clock
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type fixedClock struct {
+	at time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.at
+}
+
+//NewFixedClock returns clock frozen at supplied time
+func NewFixedClock(at time.Time) Clock {
+	return &fixedClock{at: at}
+}
+
+//randomSource represents goroutine safe random generator consulted by random dependent udfs
+type randomSource struct {
+	mutex     sync.Mutex
+	generator *rand.Rand
+}
+
+//Float64 returns random number in [0.0,1.0)
+func (r *randomSource) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.generator.Float64()
+}
+
+func newRandomSource(source rand.Source) *randomSource {
+	return &randomSource{generator: rand.New(source)}
+}
+
+//SetClock sets clock consulted by time dependent udfs, i.e. CurrentHour, FormatTime
+func (d *Dao) SetClock(clock Clock) {
+	d.clock = clock
+}
+
+//SetRandomSource sets random source consulted by random dependent udfs, i.e. Rand
+func (d *Dao) SetRandomSource(source rand.Source) {
+	d.random = newRandomSource(source)
+}
+
+//SetDeterministic enables deterministic mode: the clock is frozen at supplied time, random source is seeded with the seed at each document load,
+//and calls of udfs that cannot be made deterministic (environment, external and udfs without signature) fail
+func (d *Dao) SetDeterministic(at time.Time, seed int64) {
+	d.deterministic = true
+	d.seed = seed
+	d.clock = NewFixedClock(at)
+	d.random = newRandomSource(rand.NewSource(seed))
+}
+
+//reseeded returns dao copy with random source seeded with deterministic mode seed for the outer load,
+//nested loads, i.e. $LoadNeatly, use the outer load dao copy and continue its random sequence
+func (d *Dao) reseeded() *Dao {
+	var dao = *d
+	dao.random = newRandomSource(rand.NewSource(d.seed))
+	dao.seeded = true
+	return &dao
+}
+
+//stateDao returns dao loading the document
+func stateDao(state data.Map) *Dao {
+	if state == nil {
+		return nil
+	}
+	dao, _ := state.Get(NeatlyDao).(*Dao)
+	return dao
+}
+
+//stateClock returns dao clock or the wall clock
+func stateClock(state data.Map) Clock {
+	if dao := stateDao(state); dao != nil && dao.clock != nil {
+		return dao.clock
+	}
+	return systemClock{}
+}
+
+//stateRandom returns dao random source or a new time seeded one
+func stateRandom(state data.Map) *randomSource {
+	if dao := stateDao(state); dao != nil && dao.random != nil {
+		return dao.random
+	}
+	return newRandomSource(rand.NewSource(time.Now().UnixNano()))
+}
+
+//nonDeterministicError returns an error in deterministic mode if udf category cannot be made deterministic,
+//udf without signature is an error as its category is unknown
+func nonDeterministicError(state data.Map, name string, signature *UdfSignature) error {
+	if dao := stateDao(state); dao == nil || !dao.deterministic {
+		return nil
+	}
+	if signature == nil {
+		return fmt.Errorf("%v is not deterministic (udf without signature, register it with RegisterUdf)", name)
+	}
+	switch signature.Category {
+	case UdfEnvironment, UdfExternal:
+		return fmt.Errorf("%v is not deterministic (%v udf)", signature.Name, signature.Category)
+	}
+	return nil
+}
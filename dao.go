@@ -1,8 +1,9 @@
 package neatly
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"log"
 	"path"
 	"strings"
 
@@ -17,8 +18,7 @@ const (
 	//OwnerURL represewnt currently loading neatly URL
 	OwnerURL = "ownerURL"
 	//NeatlyDao nearly dao key
-	NeatlyDao          = "nearlyDAO"
-	arrayRowTerminator = "-"
+	NeatlyDao = "nearlyDAO"
 )
 
 var commonResourceExtensions = []string{".json", ".yaml", ".txt", ".csv", ".md"}
@@ -28,35 +28,59 @@ type Dao struct {
 	includeMeta        bool
 	localResourceRepo  string
 	remoteResourceRepo string
-	factory            toolbox.DecoderFactory
 	converter          *toolbox.Converter
+	inferTypes         bool
+	sourceMap          bool
+	validateUdfs       bool
+	externalUdfs       *externalUdfs
+	clock              Clock
+	random             *randomSource
+	deterministic      bool
+	seed               int64
+	seeded             bool
 }
 
-//Load reads data from provided resource into the target pointer
-func (d *Dao) Load(context data.Map, source *url.Resource, target interface{}) error {
-	text, err := source.DownloadText()
-	if err != nil {
-		return err
+//SetUdfValidation enables or disables udf call validation (enabled by default), when enabled $Udf(...) call of unregistered udf
+//that is a near miss of a registered udf name is an error, other unregistered calls stay as literal text
+func (d *Dao) SetUdfValidation(enabled bool) {
+	d.validateUdfs = enabled
+}
+
+//SetSourceMap enables or disables building value source map returned by LoadWithMetadata
+func (d *Dao) SetSourceMap(enabled bool) {
+	d.sourceMap = enabled
+}
+
+//SetTypeInference enables or disables scalar type inference for unannotated cells, numeric values are converted to int64 or float64,
+//true/false to bool and RFC3339 values to time.Time; columns with :string suffix and single quoted values are kept as text
+func (d *Dao) SetTypeInference(enabled bool) {
+	d.inferTypes = enabled
+}
+
+//inferValue infers scalar value type if type inference is enabled for the field
+func (d *Dao) inferValue(field *Field, value interface{}) interface{} {
+	if !d.inferTypes || field.Leaf.IsText {
+		return value
 	}
-	context.Put(OwnerURL, source.URL)
-	context.Put(NeatlyDao, d)
-	AddStandardUdf(context)
-	text = strings.Replace(text, "\r", "", len(text))
-	scanner := bufio.NewScanner(strings.NewReader(text))
-	targetMap, err := d.load(context, source, scanner)
+	return inferType(value)
+}
+
+//Load reads data from provided resource into the target pointer, if target is *OrderedMap document key order is preserved
+func (d *Dao) Load(context data.Map, source *url.Resource, target interface{}) error {
+	document, err := d.Prepare(source)
 	if err != nil {
 		return err
 	}
+	return document.Execute(context, target)
+}
 
-	var sourceMap = make(map[string]interface{})
-	err = d.converter.AssignConverted(&sourceMap, source)
+//loadDocument prepares and loads neatly document, importChain holds URLs of the documents being imported to detect cycles
+func (d *Dao) loadDocument(context data.Map, source *url.Resource, importChain []string) (*tagContext, error) {
+	document, err := d.Prepare(source)
 	if err != nil {
-		return err
-	}
-	if d.includeMeta {
-		targetMap["Source"] = sourceMap
+		return nil, err
 	}
-	return d.converter.AssignConverted(target, targetMap)
+	return document.execute(context, importChain)
 }
 
 //AddStandardUdf register building udf to the context
@@ -71,71 +95,146 @@ func (d *Dao) processTag(context *tagContext) (err error) {
 		return nil
 	}
 
+	var imported interface{}
+	if context.tag.Import != "" {
+		var has bool
+		if imported, has = context.importedValue(context.tag.Import); !has {
+			return fmt.Errorf("%v - failed to resolve imported tag: %v", context.tag.Name, context.tag.Import)
+		}
+	}
 	if context.tag.IsArray {
 		var collection = data.NewCollection()
+		if imported != nil {
+			for _, item := range toolbox.AsSlice(imported) {
+				collection.Push(cloneValue(item))
+			}
+		}
 		context.objectContainer.Put(context.tag.Name, collection)
 		err = context.referenceValues.Apply(context.tag.Name, collection)
 	} else {
 		var object = make(map[string]interface{})
+		if imported != nil && toolbox.IsMap(imported) {
+			for k, v := range toolbox.AsMap(imported) {
+				object[k] = cloneValue(v)
+			}
+		}
 		context.objectContainer.Put(context.tag.Name, object)
 		err = context.referenceValues.Apply(context.tag.Name, object)
 	}
 	return err
 }
 
-//processHeaderLine extract from LineNumber a tag from column[0], add deferredRefences for a tag, decodes fields from remaining column,
-func (d *Dao) processHeaderLine(context *tagContext, decoder toolbox.Decoder, lineNumber int) (*toolbox.DelimitedRecord, *Tag, error) {
-	record := &toolbox.DelimitedRecord{Delimiter: ","}
-	err := decoder.Decode(record)
+//processImports loads documents listed by Import directives, imported document tags are registered under import alias
+func (d *Dao) processImports(context *tagContext, importSpecs []string) error {
+	for _, spec := range importSpecs {
+		URI, alias := parseImport(spec)
+		objects, err := d.importDocument(context, URI)
+		if err != nil {
+			return err
+		}
+		context.imports[alias] = objects
+	}
+	return nil
+}
+
+//importDocument loads imported document with a copy of the importing document state, it returns imported document tag objects
+func (d *Dao) importDocument(context *tagContext, URI string) (data.Map, error) {
+	resource, err := d.getExternalResource(context, URI)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	for _, URL := range context.importChain {
+		if URL == resource.URL {
+			return nil, fmt.Errorf("import cycle detected: %v -> %v", strings.Join(context.importChain, " -> "), resource.URL)
+		}
+	}
+	var state = data.NewMap()
+	for k, v := range context.context {
+		if k != OwnerURL {
+			state.Put(k, v)
+		}
 	}
+	var importChain = append(append([]string{}, context.importChain...), resource.URL)
+	document, err := d.loadDocument(state, resource, importChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %v, %v", URI, err)
+	}
+	context.keyOrder.merge(document.keyOrder)
+	context.objectKeys.merge(document.objectKeys)
+	return document.objectContainer, nil
+}
+
+//processHeaderLine extract from LineNumber a tag from column[0], add deferredRefences for a tag, decodes fields from remaining column,
+func (d *Dao) processHeaderLine(context *tagContext, line *documentLine, lineNumber int) (*toolbox.DelimitedRecord, *Tag, error) {
+	record := &toolbox.DelimitedRecord{Columns: line.columns(), Delimiter: ","}
 	ownerName := context.rootObject.GetString("Name")
 	context.tag = NewTag(ownerName, context.source, record.Columns[0], lineNumber)
-	err = d.processTag(context)
-	if err != nil {
+	context.keyOrder.add(context.tag.Name)
+	context.keyOrder.addColumns(record.Columns[1:])
+	if context.tag.Dimensions > maxTagDimensions {
+		return nil, nil, fmt.Errorf("%v - unsupported %v dimension array tag, tag can have up to %v dimensions", context.tag.Name, context.tag.Dimensions, maxTagDimensions)
+	}
+	if context.tag.KeyField != "" && context.tag.IsArray {
+		return nil, nil, fmt.Errorf("%v - unsupported keyed array tag, {%v} key field tag can not be an array", context.tag.Name, context.tag.KeyField)
+	}
+	if err := d.processTag(context); err != nil {
 		return nil, nil, err
 	}
+	if context.tag.Dimensions > 1 {
+		context.objectContainer.GetCollection(context.tag.Name).Push(data.NewCollection())
+	}
 	return record, context.tag, nil
 }
 
 //processHeaderLine extract from LineNumber a tag from column[0], add deferredRefences for a tag, decodes fields from remaining column,
-func (d *Dao) processRootHeaderLine(source *url.Resource, objectContainer data.Map, decoder toolbox.Decoder) (*toolbox.DelimitedRecord, *Tag, error) {
-	record := &toolbox.DelimitedRecord{Delimiter: ","}
-	err := decoder.Decode(record)
-	if err != nil {
-		return nil, nil, err
-	}
+func (d *Dao) processRootHeaderLine(source *url.Resource, objectContainer data.Map, line *documentLine) (*toolbox.DelimitedRecord, *Tag) {
+	record := &toolbox.DelimitedRecord{Columns: line.columns(), Delimiter: ","}
 	tag := NewTag("", source, record.Columns[0], 0)
 	var object = make(map[string]interface{})
 	objectContainer.Put(tag.Name, object)
-	return record, tag, nil
+	return record, tag
 }
 
-//load loads source using nearly format.
-func (d *Dao) load(loadingContext data.Map, source *url.Resource, scanner *bufio.Scanner) (map[string]interface{}, error) {
+//load evaluates parsed neatly document, returned error is prefixed with the source line number of the failing row
+func (d *Dao) load(loadingContext data.Map, document *Document, importChain []string) (result *tagContext, err error) {
 	var objectContainer = data.NewMap()
 	var referenceValues = newReferenceValues()
-	lines := readLines(scanner)
-	decoder := d.factory.Create(strings.NewReader(lines[0]))
-	record, tag, err := d.processRootHeaderLine(source, objectContainer, decoder)
-	if err != nil {
-		return nil, err
-	}
+	var source, lines, lineNumbers = document.source, document.lines, document.lineNumbers
+	var lineNumber = lineNumbers[0]
+	defer func() {
+		if err != nil && lineNumber > 0 {
+			var row *rowError
+			if errors.As(err, &row) {
+				lineNumber, err = row.line, row.err
+			}
+			err = fmt.Errorf("%v:%v, %v", source.URL, lineNumber, err)
+		}
+	}()
+	record, tag := d.processRootHeaderLine(source, objectContainer, lines[0])
 	var rootObject = objectContainer.GetMap(tag.Name)
 	var context = newTagContext(loadingContext, source, tag, objectContainer, referenceValues, rootObject, rootObject)
+	context.importChain = importChain
+	context.templates = document.templates
+	context.keyOrder.addColumns(record.Columns[1:])
+	context.lineNumbers = lineNumbers
+	if d.sourceMap {
+		context.positions = make(positions)
+	}
+	if err = d.processImports(context, document.importSpecs); err != nil {
+		return nil, err
+	}
 	for i := 1; i < len(lines); i++ {
 		var recordHeight = 0
 		line := lines[i]
-		if strings.HasPrefix(line, arrayRowTerminator) { //replace array terminator
-			line = strings.Replace(line, arrayRowTerminator, "", 1)
-		}
+		lineNumber = lineNumbers[i]
+		context.lineNumber = lineNumber
 		var hasActiveIterator = tag.HasActiveIterator()
-		line = d.expandMeta(context, line)
-
-		isHeaderLine := !strings.HasPrefix(line, ",")
-		decoder := d.factory.Create(strings.NewReader(line))
-		if isHeaderLine {
+		if line.contains("$") {
+			line = line.expand(func(text string) string {
+				return d.expandMeta(context, text)
+			})
+		}
+		if line.isHeader {
 			if hasActiveIterator {
 				if tag.Iterator.Next() {
 					context.tag.Subpath = ""
@@ -143,24 +242,20 @@ func (d *Dao) load(loadingContext data.Map, source *url.Resource, scanner *bufio
 					continue
 				}
 			}
-			record, tag, err = d.processHeaderLine(context, decoder, i)
+			record, tag, err = d.processHeaderLine(context, line, i)
 			if err != nil {
 				return nil, err
 			}
 			continue
 		}
 
-		record.Record = make(map[string]interface{})
-		err := decoder.Decode(record)
-		if err != nil {
-			return nil, err
-		}
+		line.decode(record)
 		if !record.IsEmpty() {
 			context.virtualObjects = data.NewMap()
 			context.fieldIndex = make(map[string]int)
 			tag.setTagObject(context, record.Record, d.includeMeta)
 
-			if strings.Contains(line, "$") {
+			if line.contains("$") {
 				for k, v := range record.Record {
 					if !toolbox.IsString(v) {
 						continue
@@ -168,6 +263,16 @@ func (d *Dao) load(loadingContext data.Map, source *url.Resource, scanner *bufio
 					record.Record[k] = d.expandMeta(context, toolbox.AsString(v))
 				}
 			}
+			if line.contains(templateCallPrefix) {
+				for k, v := range record.Record {
+					if !isTemplateCall(toolbox.AsString(v)) {
+						continue
+					}
+					if record.Record[k], err = d.expandTemplate(context, toolbox.AsString(v)); err != nil {
+						return nil, err
+					}
+				}
+			}
 
 			for j := 1; j < len(record.Columns); j++ {
 				if recordHeight, err = d.processCell(context, record, lines, i, j, recordHeight, true); err != nil {
@@ -179,8 +284,15 @@ func (d *Dao) load(loadingContext data.Map, source *url.Resource, scanner *bufio
 					return nil, err
 				}
 			}
-
+			if err = d.applyExtends(context, record); err != nil {
+				return nil, err
+			}
 			removeEmptyElements(context.tagObject)
+			if tag.KeyField != "" {
+				if err = tag.setKeyedObject(context); err != nil {
+					return nil, err
+				}
+			}
 		}
 
 		i += recordHeight
@@ -193,11 +305,12 @@ func (d *Dao) load(loadingContext data.Map, source *url.Resource, scanner *bufio
 			}
 		}
 	}
+	lineNumber = 0
 	err = referenceValues.CheckUnused()
 	if err != nil {
 		return nil, err
 	}
-	return rootObject, nil
+	return context, nil
 }
 
 func isMapValueEmpty(aMap map[string]interface{}) bool {
@@ -205,6 +318,12 @@ func isMapValueEmpty(aMap map[string]interface{}) bool {
 		if v == nil {
 			continue
 		}
+		if _, ok := v.(*explicitValue); ok {
+			return false
+		}
+		if toolbox.IsSlice(v) && len(toolbox.AsSlice(v)) == 0 {
+			continue
+		}
 		var textValue = toolbox.AsString(v)
 		if textValue == "" || textValue == "<nil>" {
 			continue
@@ -249,20 +368,30 @@ func removeEmptyElements(tagObject map[string]interface{}) {
 	}
 }
 
-func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord, lines []string, recordIndex, columnIndex int, recordHeight int, virtual bool) (int, error) {
+func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord, lines []*documentLine, recordIndex, columnIndex int, recordHeight int, virtual bool) (int, error) {
 	fieldExpression := record.Columns[columnIndex]
-	if fieldExpression == "" {
+	if fieldExpression == "" || isExtendsColumn(fieldExpression) {
 		return recordHeight, nil
 	}
 
 	field := NewField(fieldExpression)
 
 	value, has := record.Record[field.expression]
+	if virtual == field.IsVirtual {
+		var err error
+		if value, err = d.constrainedValue(context, field, record, value); err != nil {
+			return recordHeight, err
+		}
+		has = has || value != nil
+	}
 	if !has {
 		return recordHeight, nil
 	}
 
 	if !field.IsArray && toolbox.AsString(value) == "" {
+		if !virtual && field.HasArrayComponent && !field.IsVirtual && !field.IsRoot {
+			return d.processArrayValues(context, field, recordIndex, lines, record, context.tagObject, recordHeight)
+		}
 		return recordHeight, nil
 	}
 
@@ -272,28 +401,40 @@ func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord,
 
 	tagObject := context.tagObject
 	rootObject := context.rootObject
-	textValue := toolbox.AsString(value)
-
-	if strings.HasPrefix(textValue, "%%") {
-		//escape forward object tag reference
-		textValue = string(textValue[1:])
-	} else {
-		isReference := strings.HasPrefix(textValue, "%")
-		if isReference {
-			err := context.referenceValues.Add(string(textValue[1:]), field, tagObject)
-			return recordHeight, err
+	var val = value
+	var err error
+	var line, column = context.lineNumber, columnIndex + 1
+	if toolbox.IsString(value) {
+		textValue := toolbox.AsString(value)
+		if strings.HasPrefix(textValue, "%%") {
+			//escape forward object tag reference
+			textValue = string(textValue[1:])
+		} else {
+			isReference := strings.HasPrefix(textValue, "%")
+			if isReference {
+				if imported, has := context.importedValue(string(textValue[1:])); has {
+					if err = field.SetValue(cloneValue(imported), tagObject); err == nil {
+						d.recordPosition(context, field, tagObject, line, column, textValue)
+					}
+					return recordHeight, err
+				}
+				err := context.referenceValues.Add(string(textValue[1:]), field, tagObject)
+				d.recordPosition(context, field, tagObject, line, column, textValue)
+				return recordHeight, err
+			}
 		}
-	}
-	val, err := d.normalizeValue(context, textValue)
-	if err != nil {
-		return recordHeight, fmt.Errorf("%v - failed to normalizeValue %v, %v", context.tag.TagID(), textValue, err)
+		if val, err = d.normalizeValue(context, textValue); err != nil {
+			return recordHeight, fmt.Errorf("%v - failed to normalizeValue %v at column %v, %v", context.tag.TagID(), textValue, column, err)
+		}
+		val = d.inferValue(field, val)
 	}
 
+	var textValue = toolbox.AsString(value)
 	var targetObject data.Map
 	if field.IsRoot {
 		if !field.HasArrayComponent {
-			setRootField(field, rootObject, val, 0)
-			return recordHeight, err
+			d.recordPosition(context, field, rootObject, line, column, textValue, 0)
+			return recordHeight, setRootField(field, rootObject, val, 0)
 		}
 
 		var arrayPath = field.ArrayPath()
@@ -302,19 +443,26 @@ func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord,
 		}
 
 		var index = context.fieldIndex[arrayPath]
-		if field.Leaf.IsArray && toolbox.IsSlice(val) {
+		if field.Leaf.Dimensions == 1 && toolbox.IsSlice(val) {
 			for _, item := range toolbox.AsSlice(val) {
-				setRootField(field, rootObject, item, index)
+				if err = setRootField(field, rootObject, item, index); err != nil {
+					return recordHeight, err
+				}
+				d.recordPosition(context, field, rootObject, line, column, textValue, index)
 				index++
 			}
-		} else {
-			setRootField(field, rootObject, val, index)
+			return recordHeight, nil
 		}
-		return recordHeight, nil
+		if err = setRootField(field, rootObject, val, index); err == nil {
+			d.recordPosition(context, field, rootObject, line, column, textValue, index)
+		}
+		return recordHeight, err
 	}
 
+	_, isExplicit := val.(*explicitValue)
 	if field.IsVirtual {
 		targetObject = context.virtualObjects
+		val = resolveExplicitValues(val)
 	} else {
 		targetObject = tagObject
 		if field.expression == "This" && toolbox.IsMap(val) {
@@ -325,8 +473,11 @@ func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord,
 			return recordHeight, err
 		}
 	}
-	if val != nil {
-		field.Set(val, targetObject)
+	if val != nil || isExplicit {
+		if err = field.SetValue(val, targetObject); err != nil {
+			return recordHeight, fmt.Errorf("%v - %v", context.tag.TagID(), err)
+		}
+		d.recordPosition(context, field, targetObject, line, column, textValue)
 	}
 
 	if !field.IsVirtual && field.HasArrayComponent {
@@ -336,35 +487,94 @@ func (d *Dao) processCell(context *tagContext, record *toolbox.DelimitedRecord,
 
 }
 
-func (d *Dao) processArrayValues(context *tagContext, field *Field, recordIndex int, lines []string, record *toolbox.DelimitedRecord, data data.Map, recordHeight int) (int, error) {
+//constrainedValue returns header field default for an empty cell, it reports an error if the cell of a required field is empty,
+//array field constraints apply only to rows with a value at the field array path
+func (d *Dao) constrainedValue(context *tagContext, field *Field, record *toolbox.DelimitedRecord, value interface{}) (interface{}, error) {
+	leaf := field.Leaf
+	if !(leaf.HasDefault || leaf.IsRequired) || toolbox.AsString(value) != "" {
+		return value, nil
+	}
+	if field.HasArrayComponent && !hasArrayItem(record, field) {
+		return value, nil
+	}
+	if leaf.HasDefault {
+		return leaf.Default, nil
+	}
+	return nil, fmt.Errorf("%v - %v is required", context.tag.TagID(), field.expression)
+}
+
+//hasArrayItem returns true if record has a non empty value for other field with the same array path
+func hasArrayItem(record *toolbox.DelimitedRecord, field *Field) bool {
+	var arrayPath = field.ArrayPath()
+	for _, column := range record.Columns[1:] {
+		if column == "" || column == field.expression || isExtendsColumn(column) {
+			continue
+		}
+		if toolbox.AsString(record.Record[column]) == "" {
+			continue
+		}
+		if candidate := NewField(column); candidate.IsRoot == field.IsRoot && candidate.ArrayPath() == arrayPath {
+			return true
+		}
+	}
+	return false
+}
+
+//rowError represents continuation row error, it is reported with the continuation row line number
+type rowError struct {
+	line int
+	err  error
+}
+
+//Error returns row error message
+func (e *rowError) Error() string {
+	return e.err.Error()
+}
+
+//processArrayValues sets field values from continuation rows, each continuation row extends the shallowest field related array level
+//that has a non-empty value in the row, deeper array levels start a new element
+func (d *Dao) processArrayValues(context *tagContext, field *Field, recordIndex int, lines []*documentLine, record *toolbox.DelimitedRecord, data data.Map, recordHeight int) (int, error) {
 	if field.HasArrayComponent {
 		var itemCount = 0
+		var depth = field.ArrayDepth()
+		var levels = newArrayLevels(record, field)
 		for k := recordIndex + 1; k < len(lines); k++ {
-			if !strings.HasPrefix(lines[k], ",") {
+			if lines[k].isHeader || lines[k].terminated {
 				break
 			}
 
-			arrayValueDecoder := d.factory.Create(strings.NewReader(lines[k]))
 			arrayItemRecord := &toolbox.DelimitedRecord{
 				Columns:   record.Columns,
 				Delimiter: record.Delimiter,
 			}
-			err := arrayValueDecoder.Decode(arrayItemRecord)
-
-			if err != nil {
-				return 0, err
-			}
+			lines[k].decode(arrayItemRecord)
 
 			if arrayItemRecord.IsEmpty() {
 				break
 			}
-			itemValue := arrayItemRecord.Record[field.expression]
 			itemCount++
-			val, err := d.normalizeValue(context, toolbox.AsString(itemValue))
+			if level := levels.Next(arrayItemRecord); level == 0 || depth < level {
+				continue
+			}
+			itemValue, err := d.constrainedValue(context, field, arrayItemRecord, arrayItemRecord.Record[field.expression])
+			if err != nil {
+				return 0, &rowError{line: context.rowLine(k), err: err}
+			}
+			var val interface{}
+			if isTemplateCall(toolbox.AsString(itemValue)) {
+				val, err = d.expandTemplate(context, toolbox.AsString(itemValue))
+			} else {
+				if val, err = d.normalizeValue(context, toolbox.AsString(itemValue)); err == nil {
+					val = d.inferValue(field, val)
+				}
+			}
 			if err != nil {
+				return 0, &rowError{line: context.rowLine(k), err: fmt.Errorf("column %v: %v", columnPosition(record.Columns, field.expression), err)}
+			}
+			if err = field.SetValue(val, data, levels.Indexes(depth)...); err != nil {
 				return 0, err
 			}
-			field.Set(val, data, itemCount)
+			d.recordPosition(context, field, data, context.rowLine(k), columnPosition(record.Columns, field.expression), toolbox.AsString(itemValue), levels.Indexes(depth)...)
 		}
 		if recordHeight < itemCount {
 			recordHeight = itemCount
@@ -373,25 +583,73 @@ func (d *Dao) processArrayValues(context *tagContext, field *Field, recordIndex
 	return recordHeight, nil
 }
 
-func setRootField(field *Field, rootObject data.Map, val interface{}, index int) {
-	field.Set(val, rootObject, index)
-
-}
-
-func readLines(scanner *bufio.Scanner) []string {
-	var lines = make([]string, 0)
-
-	for scanner.Scan() {
-		var line = scanner.Text()
-		if len(lines) == 0 && strings.TrimSpace(line) == "" {
+//expandTemplate instantiates template for supplied $Use(...) expression, template rows are processed as the current tag rows
+//into a new object, if template defines more than one record a slice of objects is returned
+func (d *Dao) expandTemplate(context *tagContext, expression string) (interface{}, error) {
+	name, callArgs, err := parseTemplateCall(expression)
+	if err != nil {
+		return nil, err
+	}
+	template, ok := context.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%v - unknown template: %v", context.tag.TagID(), name)
+	}
+	args, err := template.Args(callArgs)
+	if err != nil {
+		return nil, err
+	}
+	record := &toolbox.DelimitedRecord{Columns: template.header.expand(args.ExpandAsText).columns(), Delimiter: ","}
+	context.keyOrder.addColumns(record.Columns[1:])
+	var lines = make([]*documentLine, len(template.rows))
+	for i, row := range template.rows {
+		lines[i] = row.expand(func(text string) string {
+			return args.ExpandAsText(d.expandMeta(context, text))
+		})
+	}
+	var templateContext = *context
+	templateContext.lineNumbers = nil
+	var result = make([]interface{}, 0)
+	for i := 0; i < len(lines); i++ {
+		var recordHeight = 0
+		lines[i].decode(record)
+		if record.IsEmpty() {
 			continue
 		}
-		if strings.HasPrefix(line, "//") {
-			continue
+		templateContext.tagObject = data.NewMap()
+		templateContext.virtualObjects = data.NewMap()
+		templateContext.fieldIndex = make(map[string]int)
+		for _, virtual := range []bool{true, false} {
+			for j := 1; j < len(record.Columns); j++ {
+				if recordHeight, err = d.processCell(&templateContext, record, lines, i, j, recordHeight, virtual); err != nil {
+					return nil, fmt.Errorf("%v - failed to expand template %v, %v", context.tag.TagID(), name, err)
+				}
+			}
+		}
+		if err = d.applyExtends(&templateContext, record); err != nil {
+			return nil, err
 		}
-		lines = append(lines, line)
+		removeEmptyElements(templateContext.tagObject)
+		result = append(result, map[string]interface{}(templateContext.tagObject))
+		i += recordHeight
+	}
+	if len(result) == 1 {
+		return result[0], nil
+	}
+	return result, nil
+}
+
+func setRootField(field *Field, rootObject data.Map, val interface{}, index int) error {
+	return field.SetValue(val, rootObject, index)
+}
+
+//parseImport parses import spec 'URI as alias', if alias is not specified, URI name without extension is used
+func parseImport(spec string) (string, string) {
+	spec = strings.TrimSpace(spec)
+	if index := strings.LastIndex(spec, " as "); index != -1 {
+		return strings.TrimSpace(spec[:index]), strings.TrimSpace(spec[index+4:])
 	}
-	return lines
+	_, name := path.Split(spec)
+	return spec, strings.Replace(name, path.Ext(name), "", 1)
 }
 
 func isExternalResource(candidate string) bool {
@@ -647,6 +905,23 @@ func (d *Dao) loadExternalResource(context *tagContext, assetURI string) (string
 }
 
 func (d *Dao) expandMeta(context *tagContext, text string) string {
+	if !strings.Contains(text, "$") {
+		return text
+	}
+	replacementMap := d.metaValues(context)
+	return replacementMap.ExpandAsText(text)
+}
+
+//metaValues returns current tag meta values: tagId, tag, pathMatch, subPath, path and iterator index,
+//values are rebuilt only if the tag, its sub path or iterator index has changed, returned map must not be modified
+func (d *Dao) metaValues(context *tagContext) data.Map {
+	var index = ""
+	if context.tag.HasActiveIterator() {
+		index = context.tag.Iterator.Index()
+	}
+	if cache := context.meta; cache != nil && cache.tag == context.tag && cache.subPath == context.tag.Subpath && cache.index == index {
+		return cache.values
+	}
 	var replacementMap = data.NewMap()
 
 	replacementMap.Put("tagId", context.tag.TagID())
@@ -658,9 +933,40 @@ func (d *Dao) expandMeta(context *tagContext, text string) string {
 		replacementMap.Put("path", path.Join(parent, context.tag.Subpath))
 	}
 	if context.tag.HasActiveIterator() {
-		replacementMap.Put("index", context.tag.Iterator.Index())
+		replacementMap.Put("index", index)
 	}
-	return replacementMap.ExpandAsText(text)
+	context.meta = &metaCache{tag: context.tag, subPath: context.tag.Subpath, index: index, values: replacementMap}
+	return replacementMap
+}
+
+//metaCache represents tag meta values built for the tag state
+type metaCache struct {
+	tag     *Tag
+	subPath string
+	index   string
+	values  data.Map
+}
+
+//expandExpressions evaluates $(...) cell expressions, identifiers are resolved from virtual objects, tag meta values and state
+func (d *Dao) expandExpressions(context *tagContext, value string) (interface{}, error) {
+	var meta data.Map
+	scope := &expressionScope{
+		state: context.context,
+		lookup: func(name string) (interface{}, bool) {
+			if value, ok := context.virtualObjects[name]; ok {
+				return value, true
+			}
+			if meta == nil {
+				meta = d.metaValues(context)
+			}
+			if value, ok := meta[name]; ok {
+				return value, true
+			}
+			value, ok := context.context[name]
+			return value, ok
+		},
+	}
+	return expandExpressions(value, scope)
 }
 
 func (d *Dao) normalizeValue(context *tagContext, value string) (interface{}, error) {
@@ -670,6 +976,33 @@ func (d *Dao) normalizeValue(context *tagContext, value string) (interface{}, er
 	if unescaped {
 		return value, nil
 	}
+	if explicit, ok := explicitValues[value]; ok {
+		return explicit, nil
+	}
+	if strings.Contains(value, expressionPrefix) {
+		expanded, err := d.expandExpressions(context, value)
+		if err != nil {
+			return nil, err
+		}
+		text, ok := expanded.(string)
+		if !ok {
+			return expanded, nil
+		}
+		value = text
+	}
+	if strings.Contains(value, "$") && strings.Contains(value, "(") {
+		expanded, has, err := d.expandUdfCalls(context, value)
+		if err != nil {
+			return nil, err
+		}
+		if has {
+			text, ok := expanded.(string)
+			if !ok {
+				return expanded, nil
+			}
+			value = text
+		}
+	}
 
 	if strings.HasPrefix(value, "$") && !strings.Contains(value, "(") {
 		return virtualObjects.Expand(value), nil
@@ -712,17 +1045,20 @@ func (d *Dao) normalizeValue(context *tagContext, value string) (interface{}, er
 }
 
 //NewDao creates a new neatly format compatible format data access object.
-//It takes localResourceRepo, remoteResourceRepo, dataFormat and optionally delimiterDecoderFactory
+//It takes localResourceRepo, remoteResourceRepo and dataFormat.
+//
+//Deprecated: delimiterDecoderFactory is not used as document cells are split by the ast parser, pass nil, a custom factory is logged and ignored.
 func NewDao(includeMeta bool, localResourceRepo, remoteResourceRepo, dataFormat string, delimiterDecoderFactory toolbox.DecoderFactory) *Dao {
-	if delimiterDecoderFactory == nil {
-		delimiterDecoderFactory = toolbox.NewDelimiterDecoderFactory()
+	if delimiterDecoderFactory != nil {
+		log.Printf("neatly: NewDao delimiterDecoderFactory is deprecated and ignored, document cells are split by the ast parser")
 	}
 	return &Dao{
 		includeMeta:        includeMeta,
 		localResourceRepo:  localResourceRepo,
 		remoteResourceRepo: remoteResourceRepo,
-		factory:            delimiterDecoderFactory,
 		converter:          toolbox.NewConverter(toolbox.DateFormatToLayout(dataFormat), ""),
+		externalUdfs:       &externalUdfs{},
+		validateUdfs:       true,
 	}
 }
 
@@ -742,6 +1078,34 @@ type tagContext struct {
 
 	tagObject      data.Map
 	virtualObjects data.Map
+
+	imports     map[string]data.Map       //imported documents tag objects keyed by alias
+	importChain []string                  //URLs of the documents being imported
+	templates   map[string]*TagTemplate   //declared tag templates
+	keyOrder    keyOrder                  //document keys order
+	objectKeys  objectKeyOrder            //header order of the keys set in each object
+	meta        *metaCache                //current tag meta values
+	metadata    objectRegistry[*Metadata] //tag objects metadata keyed by object identity
+	positions   positions                 //value positions, nil if source map is disabled
+	lineNumbers []int                     //source line numbers of the document lines
+	lineNumber  int                       //source line number of the current row
+}
+
+//importedValue returns imported tag object for alias.Tag expression
+func (c *tagContext) importedValue(expression string) (interface{}, bool) {
+	dotPosition := strings.Index(expression, ".")
+	if dotPosition == -1 {
+		return nil, false
+	}
+	objects, ok := c.imports[expression[:dotPosition]]
+	if !ok {
+		return nil, false
+	}
+	var key = expression[dotPosition+1:]
+	if objects.Has(key) {
+		return objects.Get(key), true
+	}
+	return objects.GetValue(key)
 }
 
 func newTagContext(context data.Map, source *url.Resource, tag *Tag, objectContainer data.Map, referenceValues referenceValues, rootObject data.Map, tagObject data.Map) *tagContext {
@@ -754,5 +1118,9 @@ func newTagContext(context data.Map, source *url.Resource, tag *Tag, objectConta
 		rootObject:      rootObject,
 		tagObject:       tagObject,
 		virtualObjects:  data.NewMap(),
+		imports:         make(map[string]data.Map),
+		keyOrder:        make(keyOrder),
+		objectKeys:      make(objectKeyOrder),
+		metadata:        make(objectRegistry[*Metadata]),
 	}
 }
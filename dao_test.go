@@ -1,11 +1,15 @@
 package neatly_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/viant/neatly"
+	"github.com/viant/toolbox"
 	"github.com/viant/toolbox/data"
 	"github.com/viant/toolbox/url"
+	"gopkg.in/yaml.v2"
+	"strings"
 	"testing"
 	"time"
 )
@@ -68,6 +72,12 @@ func TestDao_LoadUseCase1(t *testing.T) {
 		assert.Equal(t, 404, expect.StatusCode)
 	}
 
+	var document = make(map[string]interface{})
+	err = dao.Load(context, url.NewResource("test/use_case1.csv"), &document)
+	if assert.Nil(t, err) {
+		expect := toolbox.AsSlice(document["Expect"])
+		assert.EqualValues(t, map[string]interface{}{"Body": nil, "StatusCode": "404"}, expect[1])
+	}
 }
 
 type LineItem struct {
@@ -619,3 +629,723 @@ func TestBrokenExternalReference(t *testing.T) {
 	err := dao.Load(context, url.NewResource("test/broken3.csv"), &document)
 	assert.NotNil(t, err)
 }
+
+type UseCase15User struct {
+	Id   int
+	Name string
+}
+
+type UseCase15 struct {
+	Admin struct {
+		Id          int
+		Name        string
+		Description string
+	}
+	Users    []*UseCase15User
+	Settings map[string]interface{}
+}
+
+func TestDao_LoadUseCase15(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	context.Put("env", "test")
+	var useCase = &UseCase15{}
+	err := dao.Load(context, url.NewResource("test/use_case15.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 1, useCase.Admin.Id)
+	assert.Equal(t, "Root", useCase.Admin.Name)
+	assert.Equal(t, "Administrator account", useCase.Admin.Description)
+	if assert.Equal(t, 3, len(useCase.Users)) {
+		assert.Equal(t, "Smith", useCase.Users[0].Name)
+		assert.Equal(t, "Kowalczyk", useCase.Users[1].Name)
+		assert.Equal(t, 3, useCase.Users[2].Id)
+		assert.Equal(t, "Schmidt", useCase.Users[2].Name)
+	}
+	assert.EqualValues(t, "3", useCase.Settings["Retries"])
+	assert.EqualValues(t, "30", useCase.Settings["Timeout"])
+	assert.EqualValues(t, "env-test", useCase.Settings["Env"])
+}
+
+func TestImportCycle(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/broken4.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "import cycle detected")
+	}
+}
+
+type UseCase16Check struct {
+	Request struct {
+		Name    string
+		Method  string
+		URL     string
+		Headers map[string]string
+	}
+	Expect []struct {
+		Code int
+	}
+}
+
+type UseCase16 struct {
+	Checks []struct {
+		Name  string
+		Check *UseCase16Check
+	}
+}
+
+func TestDao_LoadUseCase16(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase16{}
+	err := dao.Load(context, url.NewResource("test/use_case16.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Equal(t, 2, len(useCase.Checks)) {
+		return
+	}
+	{
+		check := useCase.Checks[0].Check
+		assert.Equal(t, "home", useCase.Checks[0].Name)
+		assert.Equal(t, "check-404", check.Request.Name)
+		assert.Equal(t, "GET", check.Request.Method)
+		assert.Equal(t, "http://127.0.0.1/", check.Request.URL)
+		assert.Equal(t, "text/html", check.Request.Headers["Accept"])
+		if assert.Equal(t, 2, len(check.Expect)) {
+			assert.Equal(t, 404, check.Expect[0].Code)
+			assert.Equal(t, 201, check.Expect[1].Code)
+		}
+	}
+	{
+		check := useCase.Checks[1].Check
+		assert.Equal(t, "check-200", check.Request.Name)
+		assert.Equal(t, "http://127.0.0.1/api?a=1,b=2", check.Request.URL)
+		assert.Equal(t, "application/json", check.Request.Headers["Accept"])
+		if assert.Equal(t, 2, len(check.Expect)) {
+			assert.Equal(t, 200, check.Expect[0].Code)
+		}
+	}
+}
+
+func TestUnknownTemplate(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/broken5.csv"), &document)
+	assert.NotNil(t, err)
+}
+
+type UseCase17Profile struct {
+	Name   string
+	Host   string
+	Port   int
+	Labels []string
+	Pool   struct {
+		Min int
+		Max int
+	}
+	Users []struct {
+		Id   int
+		Role string
+	}
+}
+
+type UseCase17 struct {
+	Default  *UseCase17Profile
+	Profiles []*UseCase17Profile
+}
+
+func TestDao_LoadUseCase17(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase17{}
+	err := dao.Load(context, url.NewResource("test/use_case17.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "default", useCase.Default.Name)
+	assert.Equal(t, 8080, useCase.Default.Port)
+	if !assert.Equal(t, 4, len(useCase.Profiles)) {
+		return
+	}
+	{
+		profile := useCase.Profiles[0]
+		assert.Equal(t, "dev", profile.Name)
+		assert.Equal(t, "127.0.0.1", profile.Host)
+		assert.Equal(t, 9090, profile.Port)
+		assert.Equal(t, 1, profile.Pool.Min)
+		assert.Equal(t, 20, profile.Pool.Max)
+		assert.EqualValues(t, []string{"dev"}, profile.Labels)
+	}
+	{
+		profile := useCase.Profiles[1]
+		assert.Equal(t, "test", profile.Name)
+		assert.Equal(t, 9090, profile.Port)
+		assert.Equal(t, 30, profile.Pool.Max)
+		assert.EqualValues(t, []string{"dev"}, profile.Labels)
+	}
+	{
+		profile := useCase.Profiles[2]
+		assert.Equal(t, 8080, profile.Port)
+		assert.EqualValues(t, []string{"base", "prod"}, profile.Labels)
+	}
+	{
+		profile := useCase.Profiles[3]
+		if assert.Equal(t, 3, len(profile.Users)) {
+			assert.Equal(t, "reader", profile.Users[0].Role)
+			assert.Equal(t, "admin", profile.Users[1].Role)
+			assert.Equal(t, 3, profile.Users[2].Id)
+		}
+	}
+	assert.Equal(t, "default", useCase.Default.Name)
+	assert.EqualValues(t, []string{"base"}, useCase.Default.Labels)
+}
+
+type UseCase18 struct {
+	Labels  []string
+	Config  map[string]map[string]int
+	Servers []string
+	Tasks   []struct {
+		Name string
+		Tags []string
+	}
+}
+
+func TestDao_LoadUseCase18(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase18{}
+	err := dao.Load(context, url.NewResource("test/use_case18.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, []string{"a", "b", "c", "d"}, useCase.Labels)
+	assert.EqualValues(t, map[string]map[string]int{"A": {"X": 1, "Y": 2}}, useCase.Config)
+	assert.EqualValues(t, []string{"s2"}, useCase.Servers)
+	if assert.Equal(t, 2, len(useCase.Tasks)) {
+		assert.EqualValues(t, []string{"x"}, useCase.Tasks[0].Tags)
+	}
+}
+
+func TestSetOnceOperator(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/broken6.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "already set")
+	}
+}
+
+type UseCase19User struct {
+	Id    string
+	Name  string
+	Tag   string
+	TagID string
+	Roles []struct {
+		Name string
+	}
+}
+
+type UseCase19 struct {
+	Users map[string]*UseCase19User
+	Roles map[string]struct {
+		Level int
+	}
+}
+
+func TestDao_LoadUseCase19(t *testing.T) {
+	dao := neatly.NewDao(true, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase19{}
+	err := dao.Load(context, url.NewResource("test/use_case19.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Equal(t, 2, len(useCase.Users)) {
+		alice := useCase.Users["alice"]
+		assert.Equal(t, "Alice", alice.Name)
+		assert.Equal(t, "Users", alice.Tag)
+		assert.Equal(t, "Users", alice.TagID)
+		if assert.Equal(t, 1, len(alice.Roles)) {
+			assert.Equal(t, "admin", alice.Roles[0].Name)
+		}
+		assert.Equal(t, "Bob", useCase.Users["bob"].Name)
+	}
+	if assert.Equal(t, 2, len(useCase.Roles)) {
+		assert.Equal(t, 1, useCase.Roles["role1"].Level)
+		assert.Equal(t, 2, useCase.Roles["role2"].Level)
+	}
+}
+
+func TestDuplicateKey(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/broken7.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "duplicate key")
+	}
+	err = dao.Load(context, url.NewResource("test/broken22.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken22.csv:3")
+		assert.Contains(t, err.Error(), "Users - unsupported keyed array tag")
+	}
+}
+
+type UseCase20Request struct {
+	URL     string
+	Headers []struct {
+		Name  string
+		Value string
+	}
+}
+
+type UseCase20 struct {
+	Actions []struct {
+		Name string
+		Send struct {
+			Requests []*UseCase20Request
+		}
+		Expect []struct {
+			Code int
+		}
+	}
+}
+
+func TestDao_LoadUseCase20(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase20{}
+	err := dao.Load(context, url.NewResource("test/use_case20.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Equal(t, 3, len(useCase.Actions)) {
+		return
+	}
+	{
+		action := useCase.Actions[0]
+		assert.Equal(t, "a1", action.Name)
+		if assert.Equal(t, 2, len(action.Send.Requests)) {
+			request := action.Send.Requests[0]
+			assert.Equal(t, "http://a", request.URL)
+			if assert.Equal(t, 2, len(request.Headers)) {
+				assert.Equal(t, "Accept", request.Headers[0].Name)
+				assert.Equal(t, "json", request.Headers[0].Value)
+				assert.Equal(t, "Cookie", request.Headers[1].Name)
+				assert.Equal(t, "c1", request.Headers[1].Value)
+			}
+			request = action.Send.Requests[1]
+			assert.Equal(t, "http://b", request.URL)
+			if assert.Equal(t, 1, len(request.Headers)) {
+				assert.Equal(t, "xml", request.Headers[0].Value)
+			}
+		}
+		if assert.Equal(t, 2, len(action.Expect)) {
+			assert.Equal(t, 200, action.Expect[0].Code)
+			assert.Equal(t, 404, action.Expect[1].Code)
+		}
+	}
+	{
+		action := useCase.Actions[1]
+		assert.Equal(t, "a2", action.Name)
+		if assert.Equal(t, 1, len(action.Send.Requests)) {
+			request := action.Send.Requests[0]
+			assert.Equal(t, "http://c", request.URL)
+			if assert.Equal(t, 2, len(request.Headers)) {
+				assert.Equal(t, "X-Id", request.Headers[0].Name)
+				assert.Equal(t, "X-Trace", request.Headers[1].Name)
+			}
+		}
+		assert.Equal(t, 1, len(action.Expect))
+	}
+	{
+		action := useCase.Actions[2]
+		if assert.Equal(t, 2, len(action.Send.Requests)) {
+			assert.Equal(t, "http://d", action.Send.Requests[0].URL)
+			assert.Equal(t, "http://e", action.Send.Requests[1].URL)
+		}
+	}
+}
+
+func TestDao_LoadUseCase21(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case21.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var useCase = struct {
+		Grid  [][]int
+		Extra [][]int
+		Model struct {
+			Name   string
+			Layers struct {
+				Weights [][]interface{}
+			}
+		}
+		Matrix [][]struct {
+			X string
+			Y string
+		}
+	}{}
+	encoded, err := json.Marshal(document)
+	if assert.Nil(t, err) {
+		assert.Nil(t, json.Unmarshal(encoded, &useCase))
+	}
+	assert.EqualValues(t, [][]int{{1, 2}, {3, 4}}, useCase.Grid)
+	assert.EqualValues(t, [][]int{{7, 8}}, useCase.Extra)
+	assert.Equal(t, "m1", useCase.Model.Name)
+	assert.EqualValues(t, [][]interface{}{{0.1, 0.2}, {0.3, 0.4}, {"0.5"}}, useCase.Model.Layers.Weights)
+	if assert.Equal(t, 2, len(useCase.Matrix)) {
+		if assert.Equal(t, 2, len(useCase.Matrix[0])) {
+			assert.Equal(t, "3", useCase.Matrix[0][1].X)
+		}
+		if assert.Equal(t, 1, len(useCase.Matrix[1])) {
+			assert.Equal(t, "6", useCase.Matrix[1][0].Y)
+		}
+	}
+	err = dao.Load(context, url.NewResource("test/broken21.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken21.csv:3")
+		assert.Contains(t, err.Error(), "unsupported 3 dimension array tag")
+	}
+}
+
+type UseCase22 struct {
+	Name        string
+	Query       string
+	Script      string
+	Description string
+	Items       []struct {
+		Id   int
+		Body interface{}
+	}
+}
+
+func TestDao_LoadUseCase22(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var useCase = &UseCase22{}
+	err := dao.Load(context, url.NewResource("test/use_case22.csv"), useCase)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "q1", useCase.Name)
+	assert.Equal(t, "SELECT *\nFROM users\nWHERE id = 1", useCase.Query)
+	assert.Equal(t, "  echo \"hello\"\n    echo world", useCase.Script)
+	assert.Equal(t, "multi\nline", useCase.Description)
+	if assert.Equal(t, 2, len(useCase.Items)) {
+		assert.EqualValues(t, map[string]interface{}{"a": float64(1)}, useCase.Items[0].Body)
+		assert.EqualValues(t, "text", useCase.Items[1].Body)
+	}
+}
+
+func TestDao_LoadUseCase42(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case42.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	items := toolbox.AsSlice(document["Items"])
+	if assert.Equal(t, 2, len(items)) {
+		assert.EqualValues(t, `O"Brien`, toolbox.AsMap(items[0])["Name"])
+		assert.EqualValues(t, `5" screen`, toolbox.AsMap(items[0])["Size"])
+		assert.EqualValues(t, `15" wide`, toolbox.AsMap(items[1])["Size"])
+	}
+}
+
+func TestDao_LoadUseCase43(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case43.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "cd $(pwd)/bin", document["Setup"])
+	assert.EqualValues(t, "echo $(git rev-parse HEAD) > build.txt", document["Build"])
+	assert.EqualValues(t, 8081, document["Port"])
+}
+
+func TestMultiLineErrorLineNumber(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/broken8.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken8.csv:10,")
+	}
+}
+
+func TestDao_LoadUseCase23(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case23.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	name, has := document["Name"]
+	assert.True(t, has)
+	assert.Nil(t, name)
+	assert.EqualValues(t, "", document["Nick"])
+	assert.EqualValues(t, []interface{}{}, document["Tags"])
+	assert.EqualValues(t, map[string]interface{}{}, document["Meta"])
+	items := toolbox.AsSlice(document["Items"])
+	if assert.Equal(t, 3, len(items)) {
+		assert.EqualValues(t, "", toolbox.AsMap(items[0])["Note"])
+		assert.EqualValues(t, "$nullable", toolbox.AsMap(items[1])["Note"])
+		assert.EqualValues(t, "", toolbox.AsMap(items[2])["Id"])
+	}
+}
+
+func TestDao_LoadUseCase39(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	kind := func(source interface{}, state data.Map) (interface{}, error) {
+		var kinds = make([]string, 0)
+		for _, item := range toolbox.AsSlice(source) {
+			switch {
+			case toolbox.IsSlice(item):
+				kinds = append(kinds, "slice")
+			case toolbox.IsMap(item):
+				kinds = append(kinds, "map")
+			default:
+				kinds = append(kinds, fmt.Sprintf("%T", item))
+			}
+		}
+		return strings.Join(kinds, " "), nil
+	}
+	neatly.RegisterUdf(context, &neatly.UdfSignature{Name: "Kinds", Args: []string{"values..."}}, kind)
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case39.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, []interface{}{}, document["Items"])
+	assert.Nil(t, document["Nothing"])
+	assert.EqualValues(t, "slice", document["Kind"])
+	assert.EqualValues(t, "slice map", document["Kinds"])
+}
+
+func TestDao_LoadUseCase40(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case40.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var useCase = struct {
+		Actions []struct {
+			Tags     []string
+			Requests []struct {
+				URL     string
+				Headers []struct {
+					Name string
+				}
+			}
+		}
+	}{}
+	encoded, err := json.Marshal(document)
+	if !assert.Nil(t, err) || !assert.Nil(t, json.Unmarshal(encoded, &useCase)) || !assert.Equal(t, 1, len(useCase.Actions)) {
+		return
+	}
+	action := useCase.Actions[0]
+	assert.EqualValues(t, []string{"t1", "t2", "t3"}, action.Tags)
+	if assert.Equal(t, 2, len(action.Requests)) {
+		assert.Equal(t, "http://a", action.Requests[0].URL)
+		if assert.Equal(t, 2, len(action.Requests[0].Headers)) {
+			assert.Equal(t, "Cookie", action.Requests[0].Headers[1].Name)
+		}
+		assert.Equal(t, "http://b", action.Requests[1].URL)
+		if assert.Equal(t, 2, len(action.Requests[1].Headers)) {
+			assert.Equal(t, "Origin", action.Requests[1].Headers[1].Name)
+		}
+	}
+}
+
+func TestDao_LoadUseCase24(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	dao.SetTypeInference(true)
+	var context = data.NewMap()
+	var document = make(map[string]interface{})
+	err := dao.Load(context, url.NewResource("test/use_case24.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "app", document["Name"])
+	assert.EqualValues(t, int64(8080), document["Port"])
+	assert.EqualValues(t, 0.75, document["Ratio"])
+	assert.EqualValues(t, true, document["Enabled"])
+	assert.EqualValues(t, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), document["Created"])
+	assert.EqualValues(t, "02134", document["Zip"])
+	assert.EqualValues(t, "123", document["Code"])
+	assert.EqualValues(t, "1.0", document["Version"])
+	ids := toolbox.AsSlice(document["Ids"])
+	if assert.Equal(t, 2, len(ids)) {
+		assert.EqualValues(t, int64(1), toolbox.AsMap(ids[0])["Id"])
+		assert.EqualValues(t, false, toolbox.AsMap(ids[0])["Active"])
+		assert.EqualValues(t, int64(-2), toolbox.AsMap(ids[1])["Id"])
+	}
+}
+
+func TestDao_LoadUseCase25(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = neatly.OrderedMap{}
+	err := dao.Load(context, url.NewResource("test/use_case25.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, []string{"Zone", "Name", "Age", "Users", "Settings"}, document.Keys())
+	users, _ := document.Get("Users")
+	if assert.Equal(t, 2, len(toolbox.AsSlice(users))) {
+		assert.EqualValues(t, []string{"Name", "Id", "Address"}, toolbox.AsSlice(users)[0].(neatly.OrderedMap).Keys())
+	}
+	buf, err := json.Marshal(document)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"Zone":"us","Name":"app","Age":"3","Users":[{"Name":"Bob","Id":"1","Address":{"Zip":"94000","City":"SF"}},{"Name":"Ann","Id":"2","Address":{"Zip":"10001","City":"NY"}}],"Settings":{"Timeout":"30","Retry":"true"}}`, string(buf))
+	buf, err = yaml.Marshal(document)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(buf), "Zone: us\nName: app\nAge: \"3\"\nUsers:\n"), string(buf))
+}
+
+func TestDao_LoadUseCase41(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var context = data.NewMap()
+	var document = neatly.OrderedMap{}
+	err := dao.Load(context, url.NewResource("test/use_case41.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	buf, err := json.Marshal(document)
+	assert.Nil(t, err)
+	assert.EqualValues(t, `{"Admins":[{"Id":"1","Name":"Bob"}],"Users":[{"Name":"Ann","Id":"2","Address":{"Zip":"10001","City":"NY"}}]}`, string(buf))
+}
+
+func TestDao_LoadWithMetadata(t *testing.T) {
+	dao := neatly.NewDao(true, "", "", "", nil)
+	var context = data.NewMap()
+	result, err := dao.LoadWithMetadata(context, url.NewResource("test/use_case26.csv"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, []string{"$", "$.Settings", "$.Users[0]", "$.Users[1]"}, result.Paths())
+	users := toolbox.AsSlice(result.Data["Users"])
+	if assert.Equal(t, 2, len(users)) {
+		assert.EqualValues(t, map[string]interface{}{"Name": "Ann", "Id": "2"}, toolbox.AsMap(users[1]))
+	}
+	assert.EqualValues(t, "Root", result.Metadata["$"].Tag)
+	assert.EqualValues(t, 2, result.Metadata["$"].Line)
+	assert.EqualValues(t, "Users", result.Metadata["$.Users[1]"].Tag)
+	assert.EqualValues(t, 6, result.Metadata["$.Users[1]"].Line)
+	assert.EqualValues(t, 8, result.Metadata["$.Settings"].Line)
+	assert.True(t, strings.HasSuffix(result.Metadata["$.Settings"].SourceURL, "test/use_case26.csv"))
+}
+
+func TestDao_LoadWithSourceMap(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	dao.SetSourceMap(true)
+	var context = data.NewMap()
+	result, err := dao.LoadWithMetadata(context, url.NewResource("test/use_case27.csv"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	var useCases = []struct {
+		path   string
+		line   int
+		column int
+		assets []string
+	}{
+		{"$.Name", 2, 2, nil},
+		{"$.Users[0].Address.City", 4, 3, nil},
+		{"$.Users[0].Tags[1]", 5, 4, nil},
+		{"$.Users[1]", 6, 0, nil},
+		{"$.Users[1].Tags[0]", 6, 4, nil},
+		{"$.Request.Method", 2, 4, []string{"use_case27_request.json"}},
+	}
+	for _, useCase := range useCases {
+		position, has := result.SourceMap.Position(useCase.path)
+		if !assert.True(t, has, useCase.path) {
+			continue
+		}
+		assert.EqualValues(t, useCase.line, position.Line, useCase.path)
+		assert.EqualValues(t, useCase.column, position.Column, useCase.path)
+		assert.EqualValues(t, useCase.assets, position.Assets, useCase.path)
+		assert.True(t, strings.HasSuffix(position.URL, "test/use_case27.csv"), useCase.path)
+	}
+	_, err = json.Marshal(result.SourceMap)
+	assert.Nil(t, err)
+}
+
+func TestDao_LoadHeaderConstraints(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case31.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	servers := toolbox.AsSlice(document["Servers"])
+	if !assert.Equal(t, 2, len(servers)) {
+		return
+	}
+	first := toolbox.AsMap(servers[0])
+	assert.EqualValues(t, "a.com", first["Host"])
+	assert.EqualValues(t, "8080", first["Port"])
+	var protocols = make([]interface{}, 0)
+	for _, port := range toolbox.AsSlice(first["Ports"]) {
+		protocols = append(protocols, toolbox.AsMap(port)["Protocol"])
+	}
+	assert.EqualValues(t, []interface{}{"tcp", "udp", "tcp"}, protocols)
+	second := toolbox.AsMap(servers[1])
+	assert.EqualValues(t, "9090", second["Port"])
+	_, has := second["Ports"]
+	assert.False(t, has)
+
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken13.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken13.csv:5")
+		assert.Contains(t, err.Error(), "Host* is required")
+	}
+}
+
+func TestDao_LoadExpressions(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case32.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, 8081, document["Port"])
+	assert.EqualValues(t, "app-Root", document["Label"])
+	assert.EqualValues(t, 3, document["Replicas"])
+	assert.EqualValues(t, 2, document["UserCount"])
+	assert.EqualValues(t, "h2", document["Host"])
+	assert.EqualValues(t, "http://app:8081/", document["URL"])
+	assert.EqualValues(t, "d2a57dc1d883fd21fb9951699df71cc7", document["Digest"])
+	items := toolbox.AsSlice(document["Items"])
+	if assert.Equal(t, 2, len(items)) {
+		assert.EqualValues(t, map[string]interface{}{"Id": int64(20), "Even": true}, items[1])
+	}
+
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken14.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken14.csv:2")
+		assert.Contains(t, err.Error(), "column 3")
+		assert.Contains(t, err.Error(), `expression "port +" at 6: unexpected end of expression`)
+	}
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken23.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken23.csv:3, column 3: ")
+		assert.NotContains(t, err.Error(), "line 3")
+	}
+}
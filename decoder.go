@@ -0,0 +1,413 @@
+package neatly
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+//DecodingError represents strict decoding error with the value source position
+type DecodingError struct {
+	Path     string    //output value JSON path
+	Position *Position //value source position, nil if unknown
+	Message  string
+}
+
+//Error returns error message prefixed with the source position
+func (e *DecodingError) Error() string {
+	if e.Position == nil {
+		return fmt.Sprintf("%v: %v", e.Path, e.Message)
+	}
+	if e.Position.Column == 0 {
+		return fmt.Sprintf("%v:%v, %v: %v", e.Position.URL, e.Position.Line, e.Path, e.Message)
+	}
+	return fmt.Sprintf("%v:%v:%v, %v: %v", e.Position.URL, e.Position.Line, e.Position.Column, e.Path, e.Message)
+}
+
+//LoadAs loads neatly document into a new value of T type with strict decoding
+func LoadAs[T any](dao *Dao, state data.Map, resource *url.Resource) (T, error) {
+	var result T
+	err := dao.LoadInto(state, resource, &result)
+	return result, err
+}
+
+//LoadInto loads neatly document into the target pointer with strict decoding: unknown fields, type mismatches and overflows are reported
+//with the source position, target types can implement encoding.TextUnmarshaler or json.Unmarshaler
+func (d *Dao) LoadInto(state data.Map, source *url.Resource, target interface{}) error {
+	document, err := d.Prepare(source)
+	if err != nil {
+		return err
+	}
+	return document.ExecuteInto(state, target)
+}
+
+//ExecuteInto loads prepared document with supplied state into the target pointer with strict decoding
+func (d *Document) ExecuteInto(state data.Map, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+		return fmt.Errorf("expected non nil pointer target, but had %T", target)
+	}
+	var dao = *d.dao
+	dao.sourceMap = true
+	dao.includeMeta = false
+	var document = *d
+	document.dao = &dao
+	context, err := document.execute(state, []string{d.source.URL})
+	if err != nil {
+		return err
+	}
+	var value = resolveExplicitValues(map[string]interface{}(context.rootObject)).(map[string]interface{})
+	decoder := &strictDecoder{sourceMap: buildSourceMap(value, context.positions, context.metadata)}
+	return decoder.decode("$", value, targetValue.Elem())
+}
+
+//strictDecoder decodes loaded document tree into typed target
+type strictDecoder struct {
+	sourceMap SourceMap
+}
+
+func (d *strictDecoder) error(path string, format string, args ...interface{}) error {
+	position, _ := d.sourceMap.Position(path)
+	return &DecodingError{Path: path, Position: position, Message: fmt.Sprintf(format, args...)}
+}
+
+func (d *strictDecoder) decode(path string, source interface{}, target reflect.Value) error {
+	if target.Kind() == reflect.Ptr {
+		if source == nil {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		return d.decode(path, source, target.Elem())
+	}
+	if source == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	if handled, err := d.unmarshal(path, source, target); handled {
+		return err
+	}
+	switch target.Kind() {
+	case reflect.Interface:
+		if target.NumMethod() > 0 {
+			return d.error(path, "unsupported interface type %v", target.Type())
+		}
+		target.Set(reflect.ValueOf(plainValue(source)))
+		return nil
+	case reflect.String:
+		switch actual := source.(type) {
+		case string:
+			target.SetString(actual)
+		case bool, int, int64, float64:
+			target.SetString(toolbox.AsString(actual))
+		default:
+			return d.mismatch(path, source, target)
+		}
+		return nil
+	case reflect.Bool:
+		switch actual := source.(type) {
+		case bool:
+			target.SetBool(actual)
+		case string:
+			value, err := strconv.ParseBool(strings.TrimSpace(actual))
+			if err != nil {
+				return d.mismatch(path, source, target)
+			}
+			target.SetBool(value)
+		default:
+			return d.mismatch(path, source, target)
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return d.decodeInt(path, source, target)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return d.decodeUint(path, source, target)
+	case reflect.Float32, reflect.Float64:
+		return d.decodeFloat(path, source, target)
+	case reflect.Struct:
+		aMap, ok := asStringKeyMap(source)
+		if !ok {
+			return d.mismatch(path, source, target)
+		}
+		return d.decodeStruct(path, aMap, target)
+	case reflect.Map:
+		aMap, ok := asStringKeyMap(source)
+		if !ok {
+			return d.mismatch(path, source, target)
+		}
+		return d.decodeMap(path, aMap, target)
+	case reflect.Slice:
+		if text, ok := source.(string); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+			target.SetBytes([]byte(text))
+			return nil
+		}
+		if !toolbox.IsSlice(source) {
+			return d.mismatch(path, source, target)
+		}
+		items := toolbox.AsSlice(source)
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := d.decode(fmt.Sprintf("%v[%v]", path, i), item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case reflect.Array:
+		if !toolbox.IsSlice(source) {
+			return d.mismatch(path, source, target)
+		}
+		items := toolbox.AsSlice(source)
+		if len(items) > target.Len() {
+			return d.error(path, "array of %v elements overflows %v", len(items), target.Type())
+		}
+		for i, item := range items {
+			if err := d.decode(fmt.Sprintf("%v[%v]", path, i), item, target.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	sourceValue := reflect.ValueOf(source)
+	if sourceValue.Type().AssignableTo(target.Type()) {
+		target.Set(sourceValue)
+		return nil
+	}
+	return d.mismatch(path, source, target)
+}
+
+//unmarshal uses target encoding.TextUnmarshaler or json.Unmarshaler if implemented
+func (d *strictDecoder) unmarshal(path string, source interface{}, target reflect.Value) (bool, error) {
+	if !target.CanAddr() {
+		return false, nil
+	}
+	pointer := target.Addr()
+	text, isText := source.(string)
+	if pointer.Type().Implements(textUnmarshalerType) && isText {
+		if err := pointer.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+			return true, d.error(path, "%v", err)
+		}
+		return true, nil
+	}
+	if pointer.Type().Implements(jsonUnmarshalerType) {
+		encoded, err := json.Marshal(plainValue(source))
+		if err == nil {
+			err = pointer.Interface().(json.Unmarshaler).UnmarshalJSON(encoded)
+		}
+		if err != nil {
+			return true, d.error(path, "%v", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (d *strictDecoder) mismatch(path string, source interface{}, target reflect.Value) error {
+	if text, ok := source.(string); ok {
+		return d.error(path, "cannot decode %q into %v", text, target.Type())
+	}
+	return d.error(path, "cannot decode %T into %v", source, target.Type())
+}
+
+func (d *strictDecoder) decodeInt(path string, source interface{}, target reflect.Value) error {
+	var value int64
+	switch actual := source.(type) {
+	case string:
+		var err error
+		if value, err = strconv.ParseInt(strings.TrimSpace(actual), 10, 64); err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return d.error(path, "value %v overflows %v", actual, target.Type())
+			}
+			return d.mismatch(path, source, target)
+		}
+	case int:
+		value = int64(actual)
+	case int64:
+		value = actual
+	case float64:
+		if actual != float64(int64(actual)) {
+			return d.error(path, "value %v is not an integer, expected %v", actual, target.Type())
+		}
+		value = int64(actual)
+	default:
+		return d.mismatch(path, source, target)
+	}
+	if target.OverflowInt(value) {
+		return d.error(path, "value %v overflows %v", value, target.Type())
+	}
+	target.SetInt(value)
+	return nil
+}
+
+func (d *strictDecoder) decodeUint(path string, source interface{}, target reflect.Value) error {
+	var value uint64
+	switch actual := source.(type) {
+	case string:
+		var err error
+		if value, err = strconv.ParseUint(strings.TrimSpace(actual), 10, 64); err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return d.error(path, "value %v overflows %v", actual, target.Type())
+			}
+			return d.mismatch(path, source, target)
+		}
+	case int, int64, float64:
+		signed := toolbox.AsFloat(actual)
+		if signed < 0 || signed != float64(uint64(signed)) {
+			return d.error(path, "value %v overflows %v", actual, target.Type())
+		}
+		value = uint64(signed)
+	default:
+		return d.mismatch(path, source, target)
+	}
+	if target.OverflowUint(value) {
+		return d.error(path, "value %v overflows %v", value, target.Type())
+	}
+	target.SetUint(value)
+	return nil
+}
+
+func (d *strictDecoder) decodeFloat(path string, source interface{}, target reflect.Value) error {
+	var value float64
+	switch actual := source.(type) {
+	case string:
+		var err error
+		if value, err = strconv.ParseFloat(strings.TrimSpace(actual), 64); err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				return d.error(path, "value %v overflows %v", actual, target.Type())
+			}
+			return d.mismatch(path, source, target)
+		}
+	case int:
+		value = float64(actual)
+	case int64:
+		value = float64(actual)
+	case float64:
+		value = actual
+	default:
+		return d.mismatch(path, source, target)
+	}
+	if target.OverflowFloat(value) {
+		return d.error(path, "value %v overflows %v", value, target.Type())
+	}
+	target.SetFloat(value)
+	return nil
+}
+
+func (d *strictDecoder) decodeMap(path string, source map[string]interface{}, target reflect.Value) error {
+	mapType := target.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return d.error(path, "unsupported map key type %v", mapType.Key())
+	}
+	result := reflect.MakeMapWithSize(mapType, len(source))
+	for _, key := range sortedKeys(source) {
+		value := reflect.New(mapType.Elem()).Elem()
+		if err := d.decode(jsonPath(path, key), source[key], value); err != nil {
+			return err
+		}
+		result.SetMapIndex(reflect.ValueOf(key).Convert(mapType.Key()), value)
+	}
+	target.Set(result)
+	return nil
+}
+
+func (d *strictDecoder) decodeStruct(path string, source map[string]interface{}, target reflect.Value) error {
+	fields := structFields(target.Type())
+	var decoded = make(map[*structField]bool)
+	for _, key := range sortedKeys(source) {
+		field, ok := fields.lookup(key)
+		if !ok {
+			return d.error(jsonPath(path, key), "unknown field %v in %v", key, target.Type())
+		}
+		decoded[field] = true
+		if err := d.decode(jsonPath(path, key), source[key], fieldByIndex(target, field.index)); err != nil {
+			return err
+		}
+	}
+	for _, field := range fields {
+		if decoded[field] {
+			continue
+		}
+		if field.defaultValue != nil {
+			if err := d.decode(jsonPath(path, field.name), *field.defaultValue, fieldByIndex(target, field.index)); err != nil {
+				return err
+			}
+		} else if field.required {
+			return missingFieldError(d.sourceMap, path, field.name)
+		}
+	}
+	return nil
+}
+
+//fieldByIndex returns nested field, nil embedded struct pointers are allocated
+func fieldByIndex(target reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 && target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		target = target.Field(fieldIndex)
+	}
+	return target
+}
+
+func asStringKeyMap(source interface{}) (map[string]interface{}, bool) {
+	switch actual := source.(type) {
+	case data.Map:
+		return actual, true
+	case map[string]interface{}:
+		return actual, true
+	}
+	if toolbox.IsMap(source) {
+		return toolbox.AsMap(source), true
+	}
+	return nil, false
+}
+
+func sortedKeys(aMap map[string]interface{}) []string {
+	var result = make([]string, 0, len(aMap))
+	for key := range aMap {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+//plainValue converts data.Map and data.Collection within supplied value into plain maps and slices
+func plainValue(value interface{}) interface{} {
+	switch actual := value.(type) {
+	case data.Map:
+		return plainValue(map[string]interface{}(actual))
+	case map[string]interface{}:
+		var result = make(map[string]interface{}, len(actual))
+		for k, v := range actual {
+			result[k] = plainValue(v)
+		}
+		return result
+	case *data.Collection:
+		return plainValue([]interface{}(*actual))
+	case []interface{}:
+		var result = make([]interface{}, len(actual))
+		for i, item := range actual {
+			result[i] = plainValue(item)
+		}
+		return result
+	}
+	return value
+}
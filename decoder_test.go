@@ -0,0 +1,158 @@
+package neatly_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/neatly"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type color int
+
+func (c *color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = 1
+	case "green":
+		*c = 2
+	default:
+		return fmt.Errorf("unknown color: %s", text)
+	}
+	return nil
+}
+
+type meta struct {
+	Keys []string
+}
+
+func (m *meta) UnmarshalJSON(data []byte) error {
+	var aMap = make(map[string]interface{})
+	if err := json.Unmarshal(data, &aMap); err != nil {
+		return err
+	}
+	for key := range aMap {
+		m.Keys = append(m.Keys, key)
+	}
+	return nil
+}
+
+type typedUser struct {
+	Name string
+	Age  int
+}
+
+type typedConfig struct {
+	Name    string
+	Port    int
+	Ratio   float64
+	Enabled bool
+	Level   uint8
+	Created time.Time
+	Color   color
+	Meta    meta
+	Users   []*typedUser
+}
+
+func TestLoadAs(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	config, err := neatly.LoadAs[typedConfig](dao, data.NewMap(), url.NewResource("test/use_case29.csv"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "app", config.Name)
+	assert.EqualValues(t, 8080, config.Port)
+	assert.EqualValues(t, 0.5, config.Ratio)
+	assert.True(t, config.Enabled)
+	assert.EqualValues(t, 7, config.Level)
+	assert.EqualValues(t, time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), config.Created.UTC())
+	assert.EqualValues(t, 1, config.Color)
+	assert.EqualValues(t, []string{"a"}, config.Meta.Keys)
+	if assert.Equal(t, 2, len(config.Users)) {
+		assert.EqualValues(t, &typedUser{Name: "Ann", Age: 31}, config.Users[1])
+	}
+}
+
+func TestDao_LoadInto(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var useCases = []struct {
+		URL      string
+		position string
+		message  string
+	}{
+		{"test/broken9.csv", "broken9.csv:2:4", "$.Level: value 300 overflows uint8"},
+		{"test/broken10.csv", "broken10.csv:4:3", "$.Users[0].Nick: unknown field Nick"},
+		{"test/broken11.csv", "broken11.csv:2:3", `$.Port: cannot decode "abc" into int`},
+	}
+	for _, useCase := range useCases {
+		var config = &typedConfig{}
+		err := dao.LoadInto(data.NewMap(), url.NewResource(useCase.URL), config)
+		if !assert.NotNil(t, err, useCase.URL) {
+			continue
+		}
+		decodingError, ok := err.(*neatly.DecodingError)
+		if assert.True(t, ok, useCase.URL) {
+			assert.True(t, strings.HasSuffix(decodingError.Position.URL, useCase.URL), useCase.URL)
+		}
+		assert.Contains(t, err.Error(), useCase.position, useCase.URL)
+		assert.Contains(t, err.Error(), useCase.message, useCase.URL)
+	}
+}
+
+type TaggedBase struct {
+	Region string `neatly:"Region,default=us-east"`
+}
+
+type taggedLimits struct {
+	MaxConn int `neatly:"MaxConn,default=10"`
+}
+
+type taggedServer struct {
+	TaggedBase
+	Host   string       `neatly:"Host,required"`
+	Port   int          `neatly:"Port,default=8080"`
+	Limits taggedLimits `neatly:",inline"`
+}
+
+type taggedConfig struct {
+	Name    string          `neatly:"AppName,required"`
+	Servers []*taggedServer `neatly:"Servers"`
+}
+
+func TestDao_LoadStructTags(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var expected = []*taggedServer{
+		{TaggedBase: TaggedBase{Region: "us-east"}, Host: "a.com", Port: 8080, Limits: taggedLimits{MaxConn: 10}},
+		{TaggedBase: TaggedBase{Region: "us-east"}, Host: "b.com", Port: 8080, Limits: taggedLimits{MaxConn: 50}},
+	}
+	config, err := neatly.LoadAs[taggedConfig](dao, data.NewMap(), url.NewResource("test/use_case30.csv"))
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "app", config.Name)
+		assert.EqualValues(t, expected, config.Servers)
+	}
+	var loaded = &taggedConfig{}
+	err = dao.Load(data.NewMap(), url.NewResource("test/use_case30.csv"), loaded)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "app", loaded.Name)
+		assert.EqualValues(t, expected, loaded.Servers)
+	}
+
+	for _, load := range []func() error{
+		func() error {
+			return dao.LoadInto(data.NewMap(), url.NewResource("test/broken12.csv"), &taggedConfig{})
+		},
+		func() error {
+			return dao.Load(data.NewMap(), url.NewResource("test/broken12.csv"), &taggedConfig{})
+		},
+	} {
+		err = load()
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "broken12.csv:5,")
+			assert.Contains(t, err.Error(), "$.Servers[1].Host: missing required field Host in Servers tag")
+		}
+	}
+}
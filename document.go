@@ -0,0 +1,183 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/neatly/ast"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"reflect"
+	"strings"
+)
+
+//Document represents prepared neatly document, its nodes are parsed once into tag header and row lines,
+//a document can be executed concurrently with different states
+type Document struct {
+	dao         *Dao
+	source      *url.Resource
+	AST         *ast.Document
+	lines       []*documentLine
+	lineNumbers []int
+	importSpecs []string
+	templates   map[string]*TagTemplate
+}
+
+//documentLine represents tag header or row node cells by column position, header cells hold the tag and field expressions,
+//row cells hold values, missing cells are empty
+type documentLine struct {
+	isHeader   bool //flag indicating tag header line
+	terminated bool //flag indicating row starting with array row terminator
+	cells      []string
+}
+
+//newHeaderLine creates tag header line for supplied tag column value and header fields
+func newHeaderLine(tag string, fields []*ast.HeaderField) *documentLine {
+	var width = 1
+	for _, field := range fields {
+		if field.Column > width {
+			width = field.Column
+		}
+	}
+	var result = &documentLine{isHeader: true, cells: make([]string, width)}
+	result.cells[0] = tag
+	for _, field := range fields {
+		result.cells[field.Column-1] = field.Expression
+	}
+	return result
+}
+
+//newRowLine creates row line for supplied data or continuation row node
+func newRowLine(node ast.Node) *documentLine {
+	var result = &documentLine{}
+	var cells []*ast.Cell
+	switch actual := node.(type) {
+	case *ast.DataRow:
+		result.terminated = actual.Terminator != nil
+		result.cells, cells = make([]string, actual.Columns), actual.Cells
+	case *ast.ContinuationRow:
+		result.cells, cells = make([]string, actual.Columns), actual.Cells
+	}
+	for _, cell := range cells {
+		result.cells[cell.Column-1] = cell.Value
+	}
+	return result
+}
+
+//contains returns true if any cell contains supplied text
+func (l *documentLine) contains(text string) bool {
+	for _, cell := range l.cells {
+		if strings.Contains(cell, text) {
+			return true
+		}
+	}
+	return false
+}
+
+//expand returns a line copy with cells containing $ expanded by supplied function
+func (l *documentLine) expand(expand func(text string) string) *documentLine {
+	var result = &documentLine{isHeader: l.isHeader, terminated: l.terminated, cells: make([]string, len(l.cells))}
+	for i, cell := range l.cells {
+		if strings.Contains(cell, "$") {
+			cell = expand(cell)
+		}
+		result.cells[i] = cell
+	}
+	return result
+}
+
+//columns returns header line columns
+func (l *documentLine) columns() []string {
+	var result = make([]string, len(l.cells))
+	for i, cell := range l.cells {
+		result[i] = strings.TrimSpace(cell)
+	}
+	return result
+}
+
+//decode sets row cells as record values of the corresponding columns
+func (l *documentLine) decode(record *toolbox.DelimitedRecord) {
+	record.Record = make(map[string]interface{})
+	for i, value := range l.cells {
+		if i < len(record.Columns) {
+			record.Record[record.Columns[i]] = value
+		}
+	}
+}
+
+//Prepare downloads and parses neatly document, returned document can be executed many times with different states
+func (d *Dao) Prepare(source *url.Resource) (*Document, error) {
+	text, err := source.DownloadText()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ast.Parse(source.URL, text)
+	if err != nil {
+		return nil, fmt.Errorf("%v, %v", source.URL, err)
+	}
+	lines, lineNumbers, importSpecs, templates, err := documentLines(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("root tag was missing: %v", source.URL)
+	}
+	return &Document{
+		dao:         d,
+		source:      source,
+		AST:         parsed,
+		lines:       lines,
+		lineNumbers: lineNumbers,
+		importSpecs: importSpecs,
+		templates:   templates,
+	}, nil
+}
+
+//Execute loads prepared document with supplied state into the target pointer, if target is *OrderedMap document key order is preserved
+func (d *Document) Execute(state data.Map, target interface{}) error {
+	document, err := d.execute(state, []string{d.source.URL})
+	if err != nil {
+		return err
+	}
+	targetMap := document.rootObject
+	resolveExplicitValues(targetMap)
+
+	var sourceMap = make(map[string]interface{})
+	err = d.dao.converter.AssignConverted(&sourceMap, d.source)
+	if err != nil {
+		return err
+	}
+	if d.dao.includeMeta {
+		targetMap["Source"] = sourceMap
+	}
+	if ordered, ok := target.(*OrderedMap); ok {
+		*ordered = newOrderedMap(targetMap, document.keyOrder, document.objectKeys)
+		return nil
+	}
+	if targetType := reflect.TypeOf(target); targetType != nil && targetType.Kind() == reflect.Ptr && hasStruct(targetType.Elem()) {
+		mapper := &structMapper{sourceMap: func() SourceMap {
+			return buildSourceMap(targetMap, document.positions, document.metadata)
+		}}
+		value, err := mapper.mapValue("$", map[string]interface{}(targetMap), targetType.Elem())
+		if err != nil {
+			return err
+		}
+		return d.dao.converter.AssignConverted(target, value)
+	}
+	return d.dao.converter.AssignConverted(target, targetMap)
+}
+
+//execute evaluates prepared document with supplied state, importChain holds URLs of the documents being imported to detect cycles
+func (d *Document) execute(state data.Map, importChain []string) (*tagContext, error) {
+	if d.dao.deterministic && !d.dao.seeded {
+		var document = *d
+		document.dao = d.dao.reseeded()
+		d = &document
+	}
+	state.Put(OwnerURL, d.source.URL)
+	state.Put(NeatlyDao, d.dao)
+	AddStandardUdf(state)
+	for _, udf := range d.dao.externalUdfs.list() {
+		RegisterUdf(state, udf.signature(), udf.Call)
+	}
+	return d.dao.load(state, d, importChain)
+}
@@ -0,0 +1,82 @@
+package neatly_test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/neatly"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"sync"
+	"testing"
+)
+
+func TestDocument_Execute(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	document, err := dao.Prepare(url.NewResource("test/use_case28.csv"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		waitGroup.Add(1)
+		go func(i int) {
+			defer waitGroup.Done()
+			var state = data.NewMap()
+			state.Put("tenant", fmt.Sprintf("t%v", i))
+			var target = make(map[string]interface{})
+			if !assert.Nil(t, document.Execute(state, &target)) {
+				return
+			}
+			assert.EqualValues(t, fmt.Sprintf("hello t%v", i), target["Greeting"])
+			items := toolbox.AsSlice(target["Items"])
+			if assert.Equal(t, 2, len(items)) {
+				assert.EqualValues(t, []interface{}{"a", "b"}, toolbox.AsSlice(toolbox.AsMap(items[0])["Tags"]))
+				assert.EqualValues(t, []interface{}{"c"}, toolbox.AsSlice(toolbox.AsMap(items[1])["Tags"]))
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+}
+
+var benchmarkUseCases = []string{
+	"test/use_case1.csv",
+	"test/use_case2.csv",
+	"test/use_case3.csv",
+	"test/use_case4.csv",
+	"test/use_case7.csv",
+	"test/use_case19.csv",
+	"test/use_case22.csv",
+}
+
+func BenchmarkDao_Load(b *testing.B) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	for _, useCase := range benchmarkUseCases {
+		b.Run(useCase, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var target = make(map[string]interface{})
+				if err := dao.Load(data.NewMap(), url.NewResource(useCase), &target); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDocument_Execute(b *testing.B) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	for _, useCase := range benchmarkUseCases {
+		document, err := dao.Prepare(url.NewResource(useCase))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(useCase, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var target = make(map[string]interface{})
+				if err := document.Execute(data.NewMap(), &target); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package neatly
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"hash"
+	"hash/crc32"
+	"net/url"
+	"strings"
+)
+
+//hashes maps hmac algorithm name to its hash
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+//asBytes returns raw bytes of the value: []byte, string and collection of bytes or 0..255 integers are used as is, other values are converted with toolbox.AsString
+func asBytes(value interface{}) []byte {
+	switch actual := value.(type) {
+	case nil:
+		return []byte{}
+	case []byte:
+		return actual
+	case string:
+		return []byte(actual)
+	case []interface{}:
+		if result, ok := collectionBytes(actual); ok {
+			return result
+		}
+	}
+	return []byte(toolbox.AsString(value))
+}
+
+//collectionBytes returns collection items as bytes, ok is false if any item is not a byte or an integer within 0..255
+func collectionBytes(collection []interface{}) ([]byte, bool) {
+	var result = make([]byte, 0, len(collection))
+	for _, item := range collection {
+		switch actual := item.(type) {
+		case byte:
+			result = append(result, actual)
+		case int, int8, int16, int32, int64, uint, uint16, uint32, uint64:
+			value := toolbox.AsInt(actual)
+			if value < 0 || value > 255 {
+				return nil, false
+			}
+			result = append(result, byte(value))
+		default:
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+//hashHex returns hex digest of the value bytes
+func hashHex(newHash func() hash.Hash, source interface{}) (interface{}, error) {
+	digest := newHash()
+	if _, err := digest.Write(asBytes(source)); err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+//Sha1 computes source sha1 hex digest
+func Sha1(source interface{}, state data.Map) (interface{}, error) {
+	return hashHex(sha1.New, source)
+}
+
+//Sha256 computes source sha256 hex digest
+func Sha256(source interface{}, state data.Map) (interface{}, error) {
+	return hashHex(sha256.New, source)
+}
+
+//Sha512 computes source sha512 hex digest
+func Sha512(source interface{}, state data.Map) (interface{}, error) {
+	return hashHex(sha512.New, source)
+}
+
+//Hmac computes hmac hex digest, it takes data, key and optional algorithm: md5, sha1, sha256 (default) or sha512
+func Hmac(source interface{}, state data.Map) (interface{}, error) {
+	if !toolbox.IsSlice(source) {
+		return nil, fmt.Errorf("unable to run Hmac: expected %T, but had: %T", []interface{}{}, source)
+	}
+	args := toolbox.AsSlice(source)
+	if len(args) < 2 {
+		return nil, fmt.Errorf("unable to run Hmac, expected 2 parameters, but had: %v", len(args))
+	}
+	var algorithm = "sha256"
+	if len(args) > 2 && args[2] != "" {
+		algorithm = strings.ToLower(toolbox.AsString(args[2]))
+	}
+	newHash, ok := hashes[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Hmac algorithm %v", algorithm)
+	}
+	return hashHex(func() hash.Hash { return hmac.New(newHash, asBytes(args[1])) }, args[0])
+}
+
+//Crc32 computes source IEEE crc32 checksum
+func Crc32(source interface{}, state data.Map) (interface{}, error) {
+	return int(crc32.ChecksumIEEE(asBytes(source))), nil
+}
+
+//Hex encodes source bytes as hex text
+func Hex(source interface{}, state data.Map) (interface{}, error) {
+	return hex.EncodeToString(asBytes(source)), nil
+}
+
+//Base64Encode encodes source bytes with standard base64
+func Base64Encode(source interface{}, state data.Map) (interface{}, error) {
+	return base64.StdEncoding.EncodeToString(asBytes(source)), nil
+}
+
+//Base64Decode decodes standard, unpadded or URL safe base64 text into []byte
+func Base64Decode(source interface{}, state data.Map) (interface{}, error) {
+	text := strings.TrimSpace(string(asBytes(source)))
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var result []byte
+		if result, err = encoding.DecodeString(text); err == nil {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid base64 text: %v", err)
+}
+
+//UrlEncode URL query escapes source text
+func UrlEncode(source interface{}, state data.Map) (interface{}, error) {
+	return url.QueryEscape(string(asBytes(source))), nil
+}
+
+//UrlDecode URL query unescapes source text
+func UrlDecode(source interface{}, state data.Map) (interface{}, error) {
+	return url.QueryUnescape(string(asBytes(source)))
+}
+
+//JsonEscape escapes source text to be embedded in JSON string literal, surrounding quotes are not added
+func JsonEscape(source interface{}, state data.Map) (interface{}, error) {
+	buffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(string(asBytes(source))); err != nil {
+		return nil, err
+	}
+	encoded := strings.TrimSuffix(buffer.String(), "\n")
+	return encoded[1 : len(encoded)-1], nil
+}
@@ -0,0 +1,765 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//expressionPrefix starts computed cell expression, i.e. $(port + 1)
+const expressionPrefix = "$("
+
+//ExpressionError represents expression parsing or evaluation error, offset is 0 based position within the expression
+type ExpressionError struct {
+	Expression string
+	Offset     int
+	Message    string
+}
+
+//Error returns error message with the expression offset
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("expression %q at %v: %v", e.Expression, e.Offset, e.Message)
+}
+
+//expressionScope resolves expression identifiers and udf calls
+type expressionScope struct {
+	expression string
+	lookup     func(name string) (interface{}, bool)
+	state      data.Map
+}
+
+func (s *expressionScope) error(offset int, format string, args ...interface{}) error {
+	return &ExpressionError{Expression: s.expression, Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
+
+//expandExpressions evaluates $(...) expressions in the text, if the text is a single expression its value is returned as is,
+//otherwise expression values are substituted as text, expressions that are not evaluable are kept as is
+func expandExpressions(text string, scope *expressionScope) (interface{}, error) {
+	var result = make([]string, 0)
+	for {
+		start := strings.Index(text, expressionPrefix)
+		if start == -1 {
+			break
+		}
+		end, err := expressionEnd(text, start+len(expressionPrefix))
+		if err != nil {
+			return nil, err
+		}
+		expression := text[start+len(expressionPrefix) : end]
+		if !isEvaluable(expression, scope) {
+			result = append(result, text[:end+1])
+			text = text[end+1:]
+			continue
+		}
+		value, err := evaluateExpression(expression, scope)
+		if err != nil {
+			return nil, err
+		}
+		if start == 0 && end == len(text)-1 && len(result) == 0 {
+			return value, nil
+		}
+		result = append(result, text[:start], asText(value))
+		text = text[end+1:]
+	}
+	result = append(result, text)
+	return strings.Join(result, ""), nil
+}
+
+//isEvaluable returns false if expression can not be tokenized or refers to an identifier undefined in the scope,
+//the same way as undefined $var such text is kept as is, i.e. shell command substitution cd $(pwd)/bin
+func isEvaluable(expression string, scope *expressionScope) bool {
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return false
+	}
+	for i, token := range tokens {
+		if token.kind != tokenIdentifier {
+			continue
+		}
+		switch token.text {
+		case "true", "false", "null", "nil":
+			continue
+		}
+		if i > 0 && tokens[i-1].kind == tokenOperator && tokens[i-1].text == "." {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].kind == tokenOperator && tokens[i+1].text == "(" {
+			continue
+		}
+		if _, ok := scope.lookup(token.text); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+//expressionEnd returns position of the parenthesis closing expression that starts at offset, quoted text is skipped
+func expressionEnd(text string, offset int) (int, error) {
+	var depth = 1
+	var quote byte
+	for i := offset; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, &ExpressionError{Expression: text[offset:], Offset: len(text) - offset, Message: "missing closing parenthesis"}
+}
+
+//evaluateExpression parses and evaluates expression
+func evaluateExpression(expression string, scope *expressionScope) (interface{}, error) {
+	scope.expression = expression
+	tokens, err := tokenizeExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &expressionParser{expression: expression, tokens: tokens}
+	node, err := parser.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != tokenEnd {
+		return nil, parser.error(token.offset, "unexpected %v", token.text)
+	}
+	return node.evaluate(scope)
+}
+
+const (
+	tokenEnd = iota
+	tokenNumber
+	tokenString
+	tokenIdentifier
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind   int
+	text   string
+	value  interface{}
+	offset int
+}
+
+var expressionOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", "[", "]", ".", ","}
+
+func tokenizeExpression(expression string) ([]*expressionToken, error) {
+	var result = make([]*expressionToken, 0)
+	var runes = []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			var start = i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			var value interface{}
+			var err error
+			if strings.Contains(text, ".") {
+				value, err = strconv.ParseFloat(text, 64)
+			} else {
+				value, err = strconv.ParseInt(text, 10, 64)
+			}
+			if err != nil {
+				return nil, &ExpressionError{Expression: expression, Offset: start, Message: fmt.Sprintf("invalid number %v", text)}
+			}
+			result = append(result, &expressionToken{kind: tokenNumber, text: text, value: value, offset: start})
+		case r == '"' || r == '\'':
+			var start = i
+			var text = make([]rune, 0)
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text = append(text, runes[i])
+			}
+			if i == len(runes) {
+				return nil, &ExpressionError{Expression: expression, Offset: start, Message: "unterminated string"}
+			}
+			i++
+			result = append(result, &expressionToken{kind: tokenString, text: string(runes[start:i]), value: string(text), offset: start})
+		case r == '$' || r == '_' || unicode.IsLetter(r):
+			var start = i
+			for i++; i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])); i++ {
+			}
+			name := strings.TrimPrefix(string(runes[start:i]), "$")
+			if name == "" {
+				return nil, &ExpressionError{Expression: expression, Offset: start, Message: "unexpected $"}
+			}
+			result = append(result, &expressionToken{kind: tokenIdentifier, text: name, offset: start})
+		default:
+			var matched = ""
+			for _, operator := range expressionOperators {
+				if strings.HasPrefix(string(runes[i:]), operator) {
+					matched = operator
+					break
+				}
+			}
+			if matched == "" {
+				return nil, &ExpressionError{Expression: expression, Offset: i, Message: fmt.Sprintf("unexpected %c", r)}
+			}
+			result = append(result, &expressionToken{kind: tokenOperator, text: matched, offset: i})
+			i += len(matched)
+		}
+	}
+	return append(result, &expressionToken{kind: tokenEnd, text: "end of expression", offset: len(runes)}), nil
+}
+
+//expressionParser represents recursive descent expression parser
+type expressionParser struct {
+	expression string
+	tokens     []*expressionToken
+	index      int
+}
+
+func (p *expressionParser) error(offset int, format string, args ...interface{}) error {
+	return &ExpressionError{Expression: p.expression, Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *expressionParser) peek() *expressionToken {
+	return p.tokens[p.index]
+}
+
+func (p *expressionParser) next() *expressionToken {
+	token := p.tokens[p.index]
+	if token.kind != tokenEnd {
+		p.index++
+	}
+	return token
+}
+
+//match consumes operator token if it is one of the operators
+func (p *expressionParser) match(operators ...string) (*expressionToken, bool) {
+	token := p.peek()
+	if token.kind != tokenOperator {
+		return nil, false
+	}
+	for _, operator := range operators {
+		if token.text == operator {
+			return p.next(), true
+		}
+	}
+	return nil, false
+}
+
+func (p *expressionParser) expect(operator string) error {
+	if _, ok := p.match(operator); !ok {
+		token := p.peek()
+		return p.error(token.offset, "expected %v, but had %v", operator, token.text)
+	}
+	return nil
+}
+
+func (p *expressionParser) parseTernary() (expressionNode, error) {
+	condition, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.match("?"); !ok {
+		return condition, nil
+	}
+	whenTrue, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	whenFalse, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{condition: condition, whenTrue: whenTrue, whenFalse: whenFalse}, nil
+}
+
+//binaryPrecedence lists binary operators from the lowest precedence
+var binaryPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *expressionParser) parseBinary(level int) (expressionNode, error) {
+	if level == len(binaryPrecedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token, ok := p.match(binaryPrecedence[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{operator: token.text, left: left, right: right, offset: token.offset}
+	}
+}
+
+func (p *expressionParser) parseUnary() (expressionNode, error) {
+	if token, ok := p.match("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{operator: token.text, operand: operand, offset: token.offset}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *expressionParser) parsePostfix() (expressionNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if token, ok := p.match("."); ok {
+			name := p.next()
+			if name.kind != tokenIdentifier {
+				return nil, p.error(name.offset, "expected member name after ., but had %v", name.text)
+			}
+			node = &memberNode{target: node, name: name.text, offset: token.offset}
+		} else if token, ok := p.match("["); ok {
+			index, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{target: node, index: index, offset: token.offset}
+		} else {
+			return node, nil
+		}
+	}
+}
+
+func (p *expressionParser) parsePrimary() (expressionNode, error) {
+	token := p.next()
+	switch token.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{value: token.value}, nil
+	case tokenIdentifier:
+		switch token.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{}, nil
+		}
+		if _, ok := p.match("("); !ok {
+			return &identifierNode{name: token.text, offset: token.offset}, nil
+		}
+		var call = &callNode{name: token.text, offset: token.offset}
+		if _, ok := p.match(")"); ok {
+			return call, nil
+		}
+		for {
+			arg, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.match(","); !ok {
+				break
+			}
+		}
+		return call, p.expect(")")
+	case tokenOperator:
+		if token.text == "(" {
+			node, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		}
+	}
+	return nil, p.error(token.offset, "unexpected %v", token.text)
+}
+
+//expressionNode represents parsed expression node
+type expressionNode interface {
+	evaluate(scope *expressionScope) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) evaluate(scope *expressionScope) (interface{}, error) {
+	return n.value, nil
+}
+
+type identifierNode struct {
+	name   string
+	offset int
+}
+
+func (n *identifierNode) evaluate(scope *expressionScope) (interface{}, error) {
+	if value, ok := scope.lookup(n.name); ok {
+		return value, nil
+	}
+	return nil, scope.error(n.offset, "undefined %v", n.name)
+}
+
+type memberNode struct {
+	target expressionNode
+	name   string
+	offset int
+}
+
+func (n *memberNode) evaluate(scope *expressionScope) (interface{}, error) {
+	target, err := n.target.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || !toolbox.IsMap(target) {
+		return nil, scope.error(n.offset, "cannot access %v of %T", n.name, target)
+	}
+	value, ok := toolbox.AsMap(target)[n.name]
+	if !ok {
+		return nil, scope.error(n.offset, "undefined member %v", n.name)
+	}
+	return value, nil
+}
+
+type indexNode struct {
+	target expressionNode
+	index  expressionNode
+	offset int
+}
+
+func (n *indexNode) evaluate(scope *expressionScope) (interface{}, error) {
+	target, err := n.target.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case target != nil && toolbox.IsMap(target):
+		value, ok := toolbox.AsMap(target)[toolbox.AsString(index)]
+		if !ok {
+			return nil, scope.error(n.offset, "undefined key %v", index)
+		}
+		return value, nil
+	case target != nil && toolbox.IsSlice(target):
+		items := toolbox.AsSlice(target)
+		position, ok := asInteger(index)
+		if !ok {
+			return nil, scope.error(n.offset, "invalid index %v", index)
+		}
+		if position < 0 || position >= int64(len(items)) {
+			return nil, scope.error(n.offset, "index %v out of range [0:%v]", position, len(items))
+		}
+		return items[position], nil
+	}
+	return nil, scope.error(n.offset, "cannot index %T", target)
+}
+
+type callNode struct {
+	name   string
+	args   []expressionNode
+	offset int
+}
+
+func (n *callNode) evaluate(scope *expressionScope) (interface{}, error) {
+	var args = make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		var err error
+		if args[i], err = arg.evaluate(scope); err != nil {
+			return nil, err
+		}
+	}
+	if n.name == "len" {
+		if len(args) != 1 {
+			return nil, scope.error(n.offset, "len expects 1 argument, but had %v", len(args))
+		}
+		return valueLength(args[0]), nil
+	}
+	result, err := callUdf(scope.state, n.name, args)
+	if err != nil {
+		return nil, scope.error(n.offset, "%v", err)
+	}
+	return result, nil
+}
+
+type unaryNode struct {
+	operator string
+	operand  expressionNode
+	offset   int
+}
+
+func (n *unaryNode) evaluate(scope *expressionScope) (interface{}, error) {
+	value, err := n.operand.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	if n.operator == "!" {
+		return !isTruthy(value), nil
+	}
+	if integer, ok := asInteger(value); ok {
+		return -integer, nil
+	}
+	if number, ok := asNumber(value); ok {
+		return -number, nil
+	}
+	return nil, scope.error(n.offset, "cannot negate %v", value)
+}
+
+type ternaryNode struct {
+	condition expressionNode
+	whenTrue  expressionNode
+	whenFalse expressionNode
+}
+
+func (n *ternaryNode) evaluate(scope *expressionScope) (interface{}, error) {
+	condition, err := n.condition.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	if isTruthy(condition) {
+		return n.whenTrue.evaluate(scope)
+	}
+	return n.whenFalse.evaluate(scope)
+}
+
+type binaryNode struct {
+	operator string
+	left     expressionNode
+	right    expressionNode
+	offset   int
+}
+
+func (n *binaryNode) evaluate(scope *expressionScope) (interface{}, error) {
+	left, err := n.left.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "&&":
+		if !isTruthy(left) {
+			return false, nil
+		}
+		right, err := n.right.evaluate(scope)
+		return isTruthy(right), err
+	case "||":
+		if isTruthy(left) {
+			return true, nil
+		}
+		right, err := n.right.evaluate(scope)
+		return isTruthy(right), err
+	}
+	right, err := n.right.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+	switch n.operator {
+	case "==":
+		return isEqual(left, right), nil
+	case "!=":
+		return !isEqual(left, right), nil
+	case "<", "<=", ">", ">=":
+		return n.compare(scope, left, right)
+	case "+":
+		if _, ok := asNumber(left); !ok {
+			return toolbox.AsString(left) + toolbox.AsString(right), nil
+		}
+		if _, ok := asNumber(right); !ok {
+			return toolbox.AsString(left) + toolbox.AsString(right), nil
+		}
+	}
+	return n.arithmetic(scope, left, right)
+}
+
+func (n *binaryNode) compare(scope *expressionScope, left, right interface{}) (interface{}, error) {
+	var result int
+	leftNumber, isLeftNumber := asNumber(left)
+	rightNumber, isRightNumber := asNumber(right)
+	if isLeftNumber && isRightNumber {
+		result = compareNumbers(leftNumber, rightNumber)
+	} else {
+		result = strings.Compare(toolbox.AsString(left), toolbox.AsString(right))
+	}
+	switch n.operator {
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	}
+	return result >= 0, nil
+}
+
+func (n *binaryNode) arithmetic(scope *expressionScope, left, right interface{}) (interface{}, error) {
+	leftInteger, isLeftInteger := asInteger(left)
+	rightInteger, isRightInteger := asInteger(right)
+	if isLeftInteger && isRightInteger {
+		switch n.operator {
+		case "+":
+			return leftInteger + rightInteger, nil
+		case "-":
+			return leftInteger - rightInteger, nil
+		case "*":
+			return leftInteger * rightInteger, nil
+		}
+		if rightInteger == 0 {
+			return nil, scope.error(n.offset, "division by zero")
+		}
+		if n.operator == "%" {
+			return leftInteger % rightInteger, nil
+		}
+		return leftInteger / rightInteger, nil
+	}
+	leftNumber, isLeftNumber := asNumber(left)
+	rightNumber, isRightNumber := asNumber(right)
+	if !isLeftNumber || !isRightNumber {
+		return nil, scope.error(n.offset, "invalid operands %v %v %v", left, n.operator, right)
+	}
+	switch n.operator {
+	case "+":
+		return leftNumber + rightNumber, nil
+	case "-":
+		return leftNumber - rightNumber, nil
+	case "*":
+		return leftNumber * rightNumber, nil
+	}
+	if rightNumber == 0 {
+		return nil, scope.error(n.offset, "division by zero")
+	}
+	if n.operator == "%" {
+		return math.Mod(leftNumber, rightNumber), nil
+	}
+	return leftNumber / rightNumber, nil
+}
+
+//asInteger returns integer value of integer or integer text value
+func asInteger(value interface{}) (int64, bool) {
+	switch actual := value.(type) {
+	case int:
+		return int64(actual), true
+	case int64:
+		return actual, true
+	case int32:
+		return int64(actual), true
+	case string:
+		text := strings.TrimSpace(actual)
+		if !isNumeric(text) || strings.ContainsAny(text, ".eE") {
+			return 0, false
+		}
+		result, err := strconv.ParseInt(text, 10, 64)
+		return result, err == nil
+	}
+	return 0, false
+}
+
+//asNumber returns float value of number or numeric text value
+func asNumber(value interface{}) (float64, bool) {
+	switch actual := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return float64(toolbox.AsInt(actual)), true
+	case float32:
+		return float64(actual), true
+	case float64:
+		return actual, true
+	case string:
+		text := strings.TrimSpace(actual)
+		if !isNumeric(text) {
+			return 0, false
+		}
+		result, err := strconv.ParseFloat(text, 64)
+		return result, err == nil
+	}
+	return 0, false
+}
+
+func compareNumbers(left, right float64) int {
+	if left < right {
+		return -1
+	} else if left > right {
+		return 1
+	}
+	return 0
+}
+
+//isEqual compares numbers numerically, other values by their text representation
+func isEqual(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	leftNumber, isLeftNumber := asNumber(left)
+	rightNumber, isRightNumber := asNumber(right)
+	if isLeftNumber && isRightNumber {
+		return leftNumber == rightNumber
+	}
+	return toolbox.AsString(left) == toolbox.AsString(right)
+}
+
+//isTruthy returns false for nil, false, zero, empty and "false" values
+func isTruthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch actual := value.(type) {
+	case bool:
+		return actual
+	case string:
+		return actual != "" && actual != "false"
+	}
+	if number, ok := asNumber(value); ok {
+		return number != 0
+	}
+	return valueLength(value) != 0 || reflect.ValueOf(value).Kind() == reflect.Struct
+}
+
+//valueLength returns length of text, slice or map value
+func valueLength(value interface{}) int {
+	if value == nil {
+		return 0
+	}
+	switch {
+	case toolbox.IsString(value):
+		return len(toolbox.AsString(value))
+	case toolbox.IsSlice(value):
+		return len(toolbox.AsSlice(value))
+	case toolbox.IsMap(value):
+		return len(toolbox.AsMap(value))
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return reflected.Len()
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package neatly
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/toolbox/data"
+	"testing"
+)
+
+func Test_expandExpressions(t *testing.T) {
+	var values = map[string]interface{}{
+		"port":  "8080",
+		"ratio": 0.5,
+		"name":  "app",
+		"users": []interface{}{"a", "b"},
+		"db":    map[string]interface{}{"Hosts": []interface{}{"h1", "h2"}},
+	}
+	scope := &expressionScope{
+		state: data.NewMap(),
+		lookup: func(name string) (interface{}, bool) {
+			value, ok := values[name]
+			return value, ok
+		},
+	}
+	var useCases = []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"$(1 + 2 * 3)", int64(7)},
+		{"$((1 + 2) * 3)", int64(9)},
+		{"$(7 / 2)", int64(3)},
+		{"$(7 % 4)", int64(3)},
+		{"$(7.0 / 2)", 3.5},
+		{"$(-port + 1)", int64(-8079)},
+		{"$(ratio * 4)", 2.0},
+		{`$(name + "-" + 1)`, "app-1"},
+		{`$(name == 'app' ? "yes" : "no")`, "yes"},
+		{"$(port > 100 && !(len(users) == 0))", true},
+		{"$(port < 100 || false)", false},
+		{"$(len(name) >= 3 ? 1 : len(name) > 1 ? 2 : 3)", int64(1)},
+		{"$(db.Hosts[len(users) - 1])", "h2"},
+		{`$(db["Hosts"][0])`, "h1"},
+		{"$(null == missing)", "$(null == missing)"},
+		{"$(unknown + 1)", "$(unknown + 1)"},
+		{"cd $(pwd)/bin && echo $(date +%s)", "cd $(pwd)/bin && echo $(date +%s)"},
+		{"$(cat a | wc -l)", "$(cat a | wc -l)"},
+		{"$name:$(port + 1)", "$name:8081"},
+		{"x$(1)y$(2)z", "x1y2z"},
+		{"$((1))", int64(1)},
+	}
+	for _, useCase := range useCases {
+		actual, err := expandExpressions(useCase.expression, scope)
+		if assert.Nil(t, err, useCase.expression) {
+			assert.EqualValues(t, useCase.expected, actual, useCase.expression)
+		}
+	}
+
+	var errorCases = []struct {
+		expression string
+		expected   string
+	}{
+		{"$(1 / 0)", `expression "1 / 0" at 2: division by zero`},
+		{"$(Nope(1))", `expression "Nope(1)" at 0: undefined function Nope`},
+		{"$(users[5])", `expression "users[5]" at 5: index 5 out of range [0:2]`},
+		{`$("abc)`, `expression "\"abc)" at 5: missing closing parenthesis`},
+		{"$(1 ? 2)", `expression "1 ? 2" at 5: expected :, but had end of expression`},
+	}
+	for _, useCase := range errorCases {
+		_, err := expandExpressions(useCase.expression, scope)
+		if assert.NotNil(t, err, useCase.expression) {
+			assert.EqualValues(t, useCase.expected, err.Error(), useCase.expression)
+		}
+	}
+}
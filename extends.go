@@ -0,0 +1,176 @@
+package neatly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/viant/toolbox"
+)
+
+const (
+	extendsColumn   = "Extends"
+	previousElement = "previous"
+	//ArrayReplace replaces inherited array with the row array
+	ArrayReplace = "replace"
+	//ArrayAppend appends row array elements to inherited array
+	ArrayAppend = "append"
+	//ArrayMerge merges row array elements with inherited elements matched by key
+	ArrayMerge = "merge"
+)
+
+//Extends represents object inheritance directive, defined by Extends column, i.e. Extends, Extends(append), Extends(merge=Id)
+type Extends struct {
+	ArrayMode string //array merge mode: replace, append or merge
+	MergeKey  string //key used to match array elements in merge mode
+}
+
+//Merge deep merges override into base, override values take precedence, arrays are merged according to ArrayMode
+func (e *Extends) Merge(base, override interface{}) interface{} {
+	if base == nil {
+		return override
+	}
+	if override == nil {
+		return base
+	}
+	if toolbox.IsMap(base) && toolbox.IsMap(override) {
+		var result = make(map[string]interface{})
+		for k, v := range toolbox.AsMap(base) {
+			result[k] = v
+		}
+		for k, v := range toolbox.AsMap(override) {
+			result[k] = e.Merge(result[k], v)
+		}
+		return result
+	}
+	if toolbox.IsSlice(base) && toolbox.IsSlice(override) {
+		return e.mergeSlice(toolbox.AsSlice(base), toolbox.AsSlice(override))
+	}
+	return override
+}
+
+func (e *Extends) mergeSlice(base, override []interface{}) interface{} {
+	switch e.ArrayMode {
+	case ArrayAppend:
+		return append(append([]interface{}{}, base...), override...)
+	case ArrayMerge:
+		var result = append([]interface{}{}, base...)
+		for _, item := range override {
+			var index = e.indexOf(result, item)
+			if index == -1 {
+				result = append(result, item)
+				continue
+			}
+			result[index] = e.Merge(result[index], item)
+		}
+		return result
+	}
+	return override
+}
+
+//indexOf returns index of an element with the same merge key value as supplied item or -1
+func (e *Extends) indexOf(items []interface{}, item interface{}) int {
+	if !toolbox.IsMap(item) {
+		return -1
+	}
+	key, has := toolbox.AsMap(item)[e.MergeKey]
+	if !has {
+		return -1
+	}
+	for i, candidate := range items {
+		if !toolbox.IsMap(candidate) {
+			continue
+		}
+		if value, ok := toolbox.AsMap(candidate)[e.MergeKey]; ok && toolbox.AsString(value) == toolbox.AsString(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+//isExtendsColumn returns true if column defines Extends directive
+func isExtendsColumn(column string) bool {
+	return column == extendsColumn || strings.HasPrefix(column, extendsColumn+"(")
+}
+
+//NewExtends creates inheritance directive for supplied column
+func NewExtends(column string) (*Extends, error) {
+	var result = &Extends{ArrayMode: ArrayReplace}
+	if column == extendsColumn {
+		return result, nil
+	}
+	if !strings.HasSuffix(column, ")") {
+		return nil, fmt.Errorf("invalid %v column: %v", extendsColumn, column)
+	}
+	option := strings.TrimSpace(column[len(extendsColumn)+1 : len(column)-1])
+	pair := strings.SplitN(option, "=", 2)
+	result.ArrayMode = strings.ToLower(strings.TrimSpace(pair[0]))
+	switch result.ArrayMode {
+	case ArrayReplace, ArrayAppend:
+	case ArrayMerge:
+		if len(pair) != 2 || strings.TrimSpace(pair[1]) == "" {
+			return nil, fmt.Errorf("merge key was missing: %v, expected %v(%v=key)", column, extendsColumn, ArrayMerge)
+		}
+		result.MergeKey = strings.TrimSpace(pair[1])
+	default:
+		return nil, fmt.Errorf("unsupported %v array mode: %v", extendsColumn, option)
+	}
+	return result, nil
+}
+
+//resolveBase returns base object for supplied Extends value: previous array element, external resource or tag object
+func (d *Dao) resolveBase(context *tagContext, value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == previousElement {
+		if !context.tag.IsArray {
+			return nil, fmt.Errorf("%v - %v can only be used with array tag", context.tag.TagID(), previousElement)
+		}
+		collection := context.tag.collection(context)
+		if len(*collection) < 2 {
+			return nil, fmt.Errorf("%v - previous element was missing", context.tag.TagID())
+		}
+		return (*collection)[len(*collection)-2], nil
+	}
+	if isExternalResource(value) {
+		return d.normalizeValue(context, value)
+	}
+	if imported, has := context.importedValue(value); has {
+		return imported, nil
+	}
+	if context.objectContainer.Has(value) {
+		return context.objectContainer.Get(value), nil
+	}
+	if base, has := context.objectContainer.GetValue(value); has {
+		return base, nil
+	}
+	return nil, fmt.Errorf("%v - failed to resolve %v: %v", context.tag.TagID(), extendsColumn, value)
+}
+
+//applyExtends deep merges current tag object fields on top of the object defined by Extends column
+func (d *Dao) applyExtends(context *tagContext, record *toolbox.DelimitedRecord) error {
+	for _, column := range record.Columns[1:] {
+		if !isExtendsColumn(column) {
+			continue
+		}
+		value := toolbox.AsString(record.Record[column])
+		if value == "" {
+			return nil
+		}
+		extends, err := NewExtends(column)
+		if err != nil {
+			return err
+		}
+		base, err := d.resolveBase(context, value)
+		if err != nil {
+			return err
+		}
+		if !toolbox.IsMap(base) {
+			return fmt.Errorf("%v - unable to extend %v, expected object but had %T", context.tag.TagID(), value, base)
+		}
+		merged := toolbox.AsMap(extends.Merge(cloneValue(base), map[string]interface{}(context.tagObject)))
+		for k, v := range merged {
+			context.tagObject.Put(k, v)
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package neatly_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/neatly"
+	"testing"
+)
+
+func TestExtends_Merge(t *testing.T) {
+	var base = map[string]interface{}{
+		"A":     1,
+		"Items": []interface{}{map[string]interface{}{"Id": 1, "V": "a"}},
+		"Sub":   map[string]interface{}{"X": 1, "Y": 2},
+	}
+	var override = map[string]interface{}{
+		"Items": []interface{}{map[string]interface{}{"Id": 1, "V": "b"}, map[string]interface{}{"Id": 2}},
+		"Sub":   map[string]interface{}{"Y": 3},
+	}
+	{
+		extends, err := neatly.NewExtends("Extends")
+		assert.Nil(t, err)
+		merged := extends.Merge(base, override).(map[string]interface{})
+		assert.EqualValues(t, 1, merged["A"])
+		assert.EqualValues(t, map[string]interface{}{"X": 1, "Y": 3}, merged["Sub"])
+		assert.Equal(t, 2, len(merged["Items"].([]interface{})))
+	}
+	{
+		extends, err := neatly.NewExtends("Extends(append)")
+		assert.Nil(t, err)
+		merged := extends.Merge(base, override).(map[string]interface{})
+		assert.Equal(t, 3, len(merged["Items"].([]interface{})))
+	}
+	{
+		extends, err := neatly.NewExtends("Extends(merge=Id)")
+		assert.Nil(t, err)
+		merged := extends.Merge(base, override).(map[string]interface{})
+		items := merged["Items"].([]interface{})
+		if assert.Equal(t, 2, len(items)) {
+			assert.EqualValues(t, "b", items[0].(map[string]interface{})["V"])
+		}
+	}
+	_, err := neatly.NewExtends("Extends(merge)")
+	assert.NotNil(t, err)
+	_, err = neatly.NewExtends("Extends(zip)")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,393 @@
+package neatly
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+//UdfExternal represents external process udf category
+const UdfExternal = "external"
+
+const defaultExternalUdfTimeoutMs = 30000
+
+//ExternalUdf represents external process udf configuration, the process reads new line delimited JSON requests: {"source":..., "state":{...}}
+//from stdin and writes one line JSON response: {"result":...} or {"error":"..."} to stdout for each request
+type ExternalUdf struct {
+	Name        string
+	Description string
+	Command     string   //executable path
+	CommandArgs []string //executable arguments
+	Env         []string //additional environment variables, i.e. KEY=value
+	Args        []string //udf argument names, see UdfSignature
+	Returns     string
+	TimeoutMs   int      //per call timeout, 30s by default
+	PoolSize    int      //number of reusable processes, if 0 a new process is started per call and its stdin is closed after the request
+	StateKeys   []string //state keys passed to the process, if empty all encodable state values are passed
+}
+
+//externalUdfRequest represents external process udf request
+type externalUdfRequest struct {
+	Source interface{}            `json:"source"`
+	State  map[string]interface{} `json:"state"`
+}
+
+//externalUdfResponse represents external process udf response
+type externalUdfResponse struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error"`
+}
+
+//externalProcess represents running external udf process
+type externalProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *stderrCapture
+}
+
+func (p *externalProcess) close() {
+	_ = p.stdin.Close()
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+	_ = p.cmd.Wait()
+	_ = p.stderr.reader.Close()
+}
+
+//call writes request and reads response line, process is killed if the response is not received within timeout,
+//on error it also returns stderr output written while the request was processed
+func (p *externalProcess) call(request []byte, closeInput bool, timeout time.Duration) ([]byte, string, error) {
+	type response struct {
+		line []byte
+		err  error
+	}
+	var done = make(chan *response, 1)
+	p.stderr.start()
+	go func() {
+		_, err := p.stdin.Write(append(request, '\n'))
+		if err == nil && closeInput {
+			err = p.stdin.Close()
+		}
+		if err != nil {
+			done <- &response{err: err}
+			return
+		}
+		line, err := p.stdout.ReadBytes('\n')
+		if err == io.EOF && len(bytes.TrimSpace(line)) > 0 {
+			err = nil
+		}
+		done <- &response{line: line, err: err}
+	}()
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return nil, p.stderr.output(), result.err
+		}
+		return result.line, "", nil
+	case <-time.After(timeout):
+		_ = p.cmd.Process.Kill()
+		return nil, p.stderr.output(), fmt.Errorf("timed out after %v", timeout)
+	}
+}
+
+const (
+	maxStderrCapture   = 64 * 1024
+	stderrDrainTimeout = 100 * time.Millisecond
+)
+
+//stderrCapture reads process stderr for the process lifetime, only the last maxStderrCapture bytes are kept,
+//the buffer is reset when a request starts so that output written before the request is discarded
+type stderrCapture struct {
+	reader *os.File
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+	done   chan bool
+}
+
+//write appends output to the buffer, caller has to hold the mutex
+func (c *stderrCapture) write(data []byte) {
+	c.buffer.Write(data)
+	if excess := c.buffer.Len() - maxStderrCapture; excess > 0 {
+		c.buffer.Next(excess)
+	}
+}
+
+//start discards output captured or pending in the pipe so far
+func (c *stderrCapture) start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	discardPending(c.reader)
+	c.buffer.Reset()
+}
+
+//output returns output captured since start, it waits up to stderrDrainTimeout for the failed or killed process to close stderr
+func (c *stderrCapture) output() string {
+	select {
+	case <-c.done:
+	case <-time.After(stderrDrainTimeout):
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return strings.TrimSpace(c.buffer.String())
+}
+
+//newStderrCapture creates stderr capture reading supplied pipe until it is closed
+func newStderrCapture(reader *os.File) *stderrCapture {
+	var result = &stderrCapture{reader: reader, done: make(chan bool)}
+	go func() {
+		readStderr(result)
+		close(result.done)
+	}()
+	return result
+}
+
+//externalUdf represents registered external udf with its process pool
+type externalUdf struct {
+	*ExternalUdf
+	mutex  sync.Mutex
+	slots  chan bool
+	idle   chan *externalProcess
+	closed bool
+}
+
+func (u *externalUdf) timeout() time.Duration {
+	if u.TimeoutMs <= 0 {
+		return defaultExternalUdfTimeoutMs * time.Millisecond
+	}
+	return time.Duration(u.TimeoutMs) * time.Millisecond
+}
+
+func (u *externalUdf) start() (*externalProcess, error) {
+	cmd := exec.Command(u.Command, u.CommandArgs...)
+	cmd.Env = append(os.Environ(), u.Env...)
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer stderrWriter.Close()
+	var result = &externalProcess{cmd: cmd, stderr: newStderrCapture(stderr)}
+	cmd.Stderr = stderrWriter
+	if result.stdin, err = cmd.StdinPipe(); err != nil {
+		_ = stderr.Close()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = stderr.Close()
+		return nil, err
+	}
+	result.stdout = bufio.NewReader(stdout)
+	if err = cmd.Start(); err != nil {
+		_ = stderr.Close()
+		return nil, fmt.Errorf("failed to start %v: %v", u.Command, err)
+	}
+	return result, nil
+}
+
+//acquire returns idle pooled process or starts a new one, it waits for a free slot if all pooled processes are busy
+func (u *externalUdf) acquire() (*externalProcess, error) {
+	select {
+	case u.slots <- true:
+	case <-time.After(u.timeout()):
+		return nil, fmt.Errorf("timed out waiting for %v process", u.Name)
+	}
+	select {
+	case process := <-u.idle:
+		return process, nil
+	default:
+	}
+	process, err := u.start()
+	if err != nil {
+		<-u.slots
+	}
+	return process, err
+}
+
+//release returns healthy process to the pool, otherwise the process is closed
+func (u *externalUdf) release(process *externalProcess, healthy bool) {
+	u.mutex.Lock()
+	closed := u.closed
+	u.mutex.Unlock()
+	if healthy && !closed {
+		u.idle <- process
+	} else {
+		process.close()
+	}
+	<-u.slots
+}
+
+//Call calls external process with source and selected state
+func (u *externalUdf) Call(source interface{}, state data.Map) (interface{}, error) {
+	request, err := json.Marshal(&externalUdfRequest{Source: source, State: u.selectState(state)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %v request: %v", u.Name, err)
+	}
+	var process *externalProcess
+	if u.PoolSize > 0 {
+		process, err = u.acquire()
+	} else {
+		process, err = u.start()
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, stderr, err := process.call(request, u.PoolSize == 0, u.timeout())
+	if u.PoolSize > 0 {
+		u.release(process, err == nil)
+	} else {
+		process.close()
+	}
+	if err != nil {
+		if stderr != "" {
+			return nil, fmt.Errorf("%v, %v", err, stderr)
+		}
+		return nil, err
+	}
+	var response = &externalUdfResponse{}
+	if err = json.Unmarshal(line, response); err != nil {
+		return nil, fmt.Errorf("invalid response: %s, %v", bytes.TrimSpace(line), err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%v", response.Error)
+	}
+	return response.Result, nil
+}
+
+//selectState returns state values passed to the process, funcs and neatly internal values are skipped
+func (u *externalUdf) selectState(state data.Map) map[string]interface{} {
+	var result = make(map[string]interface{})
+	if len(u.StateKeys) > 0 {
+		for _, key := range u.StateKeys {
+			if value, ok := state.GetValue(key); ok {
+				result[key] = value
+			}
+		}
+		return result
+	}
+	for key, value := range state {
+		if value == nil || key == NeatlyDao || key == udfRegistryKey {
+			continue
+		}
+		if kind := reflect.TypeOf(value).Kind(); kind == reflect.Func || kind == reflect.Chan {
+			continue
+		}
+		if _, err := json.Marshal(value); err != nil {
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+//close closes idle pooled processes, busy processes are closed once released
+func (u *externalUdf) close() {
+	u.mutex.Lock()
+	u.closed = true
+	u.mutex.Unlock()
+	for {
+		select {
+		case process := <-u.idle:
+			process.close()
+		default:
+			return
+		}
+	}
+}
+
+func newExternalUdf(config *ExternalUdf) *externalUdf {
+	var result = &externalUdf{ExternalUdf: config}
+	if config.PoolSize > 0 {
+		result.slots = make(chan bool, config.PoolSize)
+		result.idle = make(chan *externalProcess, config.PoolSize)
+	}
+	return result
+}
+
+//AddExternalUdf registers external process udf, the udf is added to the state of each loaded document
+func (d *Dao) AddExternalUdf(config *ExternalUdf) error {
+	if config.Name == "" || config.Command == "" {
+		return fmt.Errorf("external udf name and command were empty: %v %v", config.Name, config.Command)
+	}
+	if d.externalUdfs == nil {
+		d.externalUdfs = &externalUdfs{}
+	}
+	d.externalUdfs.add(newExternalUdf(config))
+	return nil
+}
+
+//LoadExternalUdfs registers external process udfs from JSON or YAML resource with a list of ExternalUdf, YAML decoder handles both
+func (d *Dao) LoadExternalUdfs(resource *url.Resource) error {
+	text, err := resource.DownloadText()
+	if err != nil {
+		return fmt.Errorf("failed to load external udfs: %v, %v", resource.URL, err)
+	}
+	var source = make([]interface{}, 0)
+	if err = yaml.Unmarshal([]byte(text), &source); err != nil {
+		return fmt.Errorf("failed to decode external udfs: %v, %v", resource.URL, err)
+	}
+	var configs = make([]*ExternalUdf, 0)
+	if err = toolbox.DefaultConverter.AssignConverted(&configs, source); err != nil {
+		return fmt.Errorf("failed to convert external udfs: %v, %v", resource.URL, err)
+	}
+	for _, config := range configs {
+		if err := d.AddExternalUdf(config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ExternalUdfs returns registry of external process udfs
+func (d *Dao) ExternalUdfs() *UdfRegistry {
+	var result = NewUdfRegistry()
+	for _, udf := range d.externalUdfs.list() {
+		result.Register(udf.signature(), udf.Call)
+	}
+	return result
+}
+
+//Close stops pooled external udf processes
+func (d *Dao) Close() error {
+	for _, udf := range d.externalUdfs.list() {
+		udf.close()
+	}
+	return nil
+}
+
+func (u *externalUdf) signature() *UdfSignature {
+	return &UdfSignature{Name: u.Name, Description: u.Description, Args: u.Args, Returns: u.Returns, Category: UdfExternal}
+}
+
+//externalUdfs represents dao external udfs, it is shared by dao copies
+type externalUdfs struct {
+	mutex sync.RWMutex
+	udfs  []*externalUdf
+}
+
+func (e *externalUdfs) add(udf *externalUdf) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.udfs = append(e.udfs, udf)
+}
+
+func (e *externalUdfs) list() []*externalUdf {
+	if e == nil {
+		return nil
+	}
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return append([]*externalUdf{}, e.udfs...)
+}
@@ -0,0 +1,156 @@
+package neatly_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/viant/neatly"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+//TestExternalUdfHelper is not a real test, it acts as external udf process when NEATLY_UDF_HELPER is set
+func TestExternalUdfHelper(t *testing.T) {
+	operation := os.Getenv("NEATLY_UDF_HELPER")
+	if operation == "" {
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request = struct {
+			Source interface{}
+			State  map[string]interface{}
+		}{}
+		var response = make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response["error"] = err.Error()
+		}
+		switch operation {
+		case "upper":
+			response["result"] = strings.ToUpper(toolbox.AsString(request.Source))
+		case "wrap":
+			args := toolbox.AsSlice(request.Source)
+			fmt.Fprintf(os.Stderr, "wrapping %v\n", args[0])
+			switch args[0] {
+			case "fail":
+				response["error"] = "unable to wrap fail"
+			case "sleep":
+				time.Sleep(2 * time.Second)
+			default:
+				response["result"] = fmt.Sprintf("%v%v%v", args[1], args[0], args[2])
+			}
+		case "keys":
+			var keys = make([]string, 0)
+			for key := range request.State {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			response["result"] = strings.Join(keys, ",")
+		}
+		buf, _ := json.Marshal(response)
+		fmt.Printf("%s\n", buf)
+	}
+	os.Exit(0)
+}
+
+func newExternalUdf(name, operation string, poolSize int, args ...string) *neatly.ExternalUdf {
+	return &neatly.ExternalUdf{
+		Name:        name,
+		Command:     os.Args[0],
+		CommandArgs: []string{"-test.run=TestExternalUdfHelper"},
+		Env:         []string{"NEATLY_UDF_HELPER=" + operation},
+		Args:        args,
+		Returns:     "string",
+		TimeoutMs:   500,
+		PoolSize:    poolSize,
+	}
+}
+
+func TestDao_AddExternalUdf(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	defer dao.Close()
+	assert.Nil(t, dao.AddExternalUdf(newExternalUdf("Shout", "upper", 0, "text")))
+	assert.Nil(t, dao.AddExternalUdf(newExternalUdf("Wrap", "wrap", 2, "text", "left", "right")))
+	keys := newExternalUdf("StateKeys", "keys", 1, "any")
+	keys.StateKeys = []string{"text", "missing"}
+	assert.Nil(t, dao.AddExternalUdf(keys))
+	assert.NotNil(t, dao.AddExternalUdf(&neatly.ExternalUdf{Name: "NoCommand"}))
+
+	udf, ok := dao.ExternalUdfs().Lookup("Wrap")
+	if assert.True(t, ok) {
+		assert.EqualValues(t, neatly.UdfExternal, udf.Category)
+	}
+
+	for i := 0; i < 2; i++ {
+		var state = data.NewMap()
+		state.Put("text", "banana")
+		var document = make(map[string]interface{})
+		err := dao.Load(state, url.NewResource("test/use_case34.csv"), &document)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.EqualValues(t, "BANANA", document["Upper"])
+		assert.EqualValues(t, "<banana>", document["Wrapped"])
+		assert.EqualValues(t, "text", document["Keys"])
+	}
+
+	var useCases = []struct {
+		input    string
+		expected string
+	}{
+		{"fail", "Wrap: unable to wrap fail"},
+		{"sleep", "timed out after 500ms"},
+		{"apple", ""},
+	}
+	for _, useCase := range useCases {
+		var state = data.NewMap()
+		state.Put("input", useCase.input)
+		var document = make(map[string]interface{})
+		err := dao.Load(state, url.NewResource("test/broken17.csv"), &document)
+		if useCase.expected == "" {
+			assert.Nil(t, err, useCase.input)
+			assert.EqualValues(t, "<apple>", document["Wrapped"], useCase.input)
+			continue
+		}
+		if assert.NotNil(t, err, useCase.input) {
+			assert.Contains(t, err.Error(), "broken17.csv:2", useCase.input)
+			assert.Contains(t, err.Error(), useCase.expected, useCase.input)
+			if useCase.input == "sleep" {
+				assert.Contains(t, err.Error(), "wrapping sleep")
+				assert.NotContains(t, err.Error(), "wrapping banana")
+			}
+		}
+	}
+}
+
+func TestDao_LoadExternalUdfs(t *testing.T) {
+	configURL := path.Join(os.TempDir(), "neatly_external_udfs.json")
+	config, err := json.Marshal([]*neatly.ExternalUdf{newExternalUdf("Shout", "upper", 0, "text")})
+	if !assert.Nil(t, err) {
+		return
+	}
+	if !assert.Nil(t, os.WriteFile(configURL, config, 0644)) {
+		return
+	}
+	defer os.Remove(configURL)
+	dao := neatly.NewDao(false, "", "", "", nil)
+	defer dao.Close()
+	if !assert.Nil(t, dao.LoadExternalUdfs(url.NewResource(configURL))) {
+		return
+	}
+	udf, ok := dao.ExternalUdfs().Lookup("Shout")
+	if assert.True(t, ok) {
+		result, err := udf.Func("apple", data.NewMap())
+		assert.Nil(t, err)
+		assert.EqualValues(t, "APPLE", result)
+	}
+	assert.NotNil(t, dao.LoadExternalUdfs(url.NewResource(path.Join(os.TempDir(), "neatly_missing_udfs.json"))))
+}
@@ -1,10 +1,12 @@
 package neatly
 
 import (
+	"fmt"
+	"github.com/viant/neatly/ast"
 	"github.com/viant/toolbox"
 	"github.com/viant/toolbox/data"
+	"regexp"
 	"strings"
-	"unicode"
 )
 
 //Field represent field of object
@@ -13,16 +15,71 @@ type Field struct {
 	Field             string //actual expression field
 	Child             *Field //child expression if expression contains .
 	IsArray           bool   //is this filed an array type
+	Dimensions        int    //number of array dimensions, i.e. 2 for [][]Grid
 	HasSubPath        bool   //flag indicating if this field has sub fields
 	HasArrayComponent bool   //flag indicating if this or child fieds have an array component
 	IsRoot            bool   //flag indicating if this field is Root
 	IsVirtual         bool   //flag indicating if this field belong to virtual object
 	IsIndex           bool   //flag indicating if this filed is actual array index, as opposed to sub field name
+	Operator          string //explicit set operator: + append, = replace, & deep merge, ! set once
+	IsText            bool   //flag indicating that field values are not subject to type inference, set with :string suffix
+	IsRequired        bool   //flag indicating that empty cell is an error, set with * suffix, i.e. Name*
+	HasDefault        bool   //flag indicating that empty cell takes Default value, set with =literal suffix, i.e. Port=8080
+	Default           string //default cell value
 	Leaf              *Field //leaf field
 }
 
-//Set sets value into target map, if indexes are provided value will be pushed into a slice
+const (
+	//AppendOperator appends value to existing slice
+	AppendOperator = "+"
+	//ReplaceOperator replaces existing value
+	ReplaceOperator = "="
+	//MergeOperator deep merges value into existing value
+	MergeOperator = "&"
+	//SetOnceOperator reports an error if value has been already set
+	SetOnceOperator = "!"
+)
+
+var fieldOperators = []string{AppendOperator, ReplaceOperator, MergeOperator, SetOnceOperator}
+
+//textFieldSuffix disables type inference for a field
+const textFieldSuffix = ":string"
+
+//requiredFieldSuffix makes an empty cell an error
+const requiredFieldSuffix = "*"
+
+//defaultLiteral matches header field default value: number, boolean or a word without spaces and '=', i.e. 8080, true, tcp
+var defaultLiteral = regexp.MustCompile(`^[A-Za-z0-9_.:/@+-]+$`)
+
+//escapedAssignment is used in header field name to keep '=' character as part of the name, i.e. a\=b
+const escapedAssignment = `\=`
+
+//splitDefault returns header field expression and its default value if expression ends with =literal, escaped '=' is unescaped
+func splitDefault(expression string) (string, *string) {
+	var index = -1
+	for i := 1; i < len(expression)-1; i++ {
+		if expression[i] == '=' && expression[i-1] != '\\' {
+			index = i
+			break
+		}
+	}
+	var defaultValue *string
+	if index > 0 && defaultLiteral.MatchString(expression[index+1:]) {
+		value := string(expression[index+1:])
+		defaultValue = &value
+		expression = string(expression[:index])
+	}
+	return strings.Replace(expression, escapedAssignment, ReplaceOperator, -1), defaultValue
+}
+
+//Set sets value into target map, if indexes are provided value will be pushed into a slice, use SetValue to get field operator error
 func (f *Field) Set(value interface{}, target data.Map, indexes ...int) {
+	_ = f.SetValue(value, target, indexes...)
+}
+
+//SetValue sets value into target map, if indexes are provided value will be pushed into a slice,
+//it returns an error if field operator fails, i.e. set once field has been already set
+func (f *Field) SetValue(value interface{}, target data.Map, indexes ...int) error {
 
 	var index = 0
 	if !target.Has(f.Field) {
@@ -34,17 +91,22 @@ func (f *Field) Set(value interface{}, target data.Map, indexes ...int) {
 	}
 
 	var aMap data.Map
-	var action func(data data.Map, indexes ...int)
+	var action func(data data.Map, indexes ...int) error
 
 	if !f.HasSubPath {
 
 		if f.IsArray {
-			action = func(object data.Map, indexes ...int) {
+			action = func(object data.Map, indexes ...int) error {
 				collection := target.GetCollection(f.Field)
-				(*collection)[index] = value
+				(*collection)[index] = asDimensions(value, f.Dimensions-1)
+				return nil
+			}
+		} else if f.Operator != "" {
+			action = func(data data.Map, indexes ...int) error {
+				return f.apply(value, data)
 			}
 		} else {
-			action = func(data data.Map, indexes ...int) {
+			action = func(data data.Map, indexes ...int) error {
 				var isValueSet = false
 				if data.Has(f.Field) {
 					existingValue := data.Get(f.Field)
@@ -69,11 +131,12 @@ func (f *Field) Set(value interface{}, target data.Map, indexes ...int) {
 				if !isValueSet {
 					data.Put(f.Field, value)
 				}
+				return nil
 			}
 		}
 
 	} else {
-		action = func(data data.Map, indexes ...int) {
+		action = func(data data.Map, indexes ...int) error {
 			if f.Child.IsIndex {
 				collection := target.GetCollection(f.Field)
 				var index = toolbox.AsInt(f.Child.Field)
@@ -81,17 +144,17 @@ func (f *Field) Set(value interface{}, target data.Map, indexes ...int) {
 					*collection = append(*collection, nil)
 				}
 				(*collection)[index] = value
-				return
+				return nil
 
 			}
-			f.Child.Set(value, data, indexes...)
+			return f.Child.SetValue(value, data, indexes...)
 		}
 	}
 
 	if f.IsArray {
 		if collectionPointer, ok := value.(*data.Collection); ok {
 			target.Put(f.Field, collectionPointer)
-			return
+			return nil
 		}
 
 		index, indexes = shiftIndex(indexes...)
@@ -104,7 +167,45 @@ func (f *Field) Set(value interface{}, target data.Map, indexes ...int) {
 	} else {
 		aMap = target
 	}
-	action(aMap, indexes...)
+	return action(aMap, indexes...)
+}
+
+//apply sets value into target map using field operator
+func (f *Field) apply(value interface{}, target data.Map) error {
+	existingValue, has := target[f.Field]
+	if !has || existingValue == nil {
+		if f.Operator == AppendOperator && !toolbox.IsSlice(value) {
+			value = []interface{}{value}
+		}
+		target.Put(f.Field, value)
+		return nil
+	}
+	switch f.Operator {
+	case SetOnceOperator:
+		return fmt.Errorf("%v has been already set", f.expression)
+	case AppendOperator:
+		var collection = data.NewCollection()
+		if toolbox.IsSlice(existingValue) {
+			for _, item := range toolbox.AsSlice(existingValue) {
+				collection.Push(item)
+			}
+		} else {
+			collection.Push(existingValue)
+		}
+		if toolbox.IsSlice(value) {
+			for _, item := range toolbox.AsSlice(value) {
+				collection.Push(item)
+			}
+		} else {
+			collection.Push(value)
+		}
+		target.Put(f.Field, collection)
+	case MergeOperator:
+		target.Put(f.Field, (&Extends{ArrayMode: ArrayReplace}).Merge(existingValue, value))
+	default:
+		target.Put(f.Field, value)
+	}
+	return nil
 }
 
 //ArrayPath returns a field array path
@@ -125,7 +226,33 @@ func (f *Field) ArrayPath() string {
 	return strings.Join(result, ".")
 }
 
-//GetArraySize  returns field array size
+//ArrayDepth returns number of array components in the field path
+func (f *Field) ArrayDepth() int {
+	var result = 0
+	for field := f; field != nil; field = field.Child {
+		if field.IsArray {
+			result++
+		}
+	}
+	return result
+}
+
+//asDimensions wraps non slice values, so that value has requested number of array dimensions
+func asDimensions(value interface{}, dimensions int) interface{} {
+	if dimensions <= 0 || value == nil {
+		return value
+	}
+	if !toolbox.IsSlice(value) {
+		value = []interface{}{value}
+	}
+	var result = make([]interface{}, 0)
+	for _, item := range toolbox.AsSlice(value) {
+		result = append(result, asDimensions(item, dimensions-1))
+	}
+	return result
+}
+
+//GetArraySize  returns field array size, for multi dimension array it returns size of the outer dimension
 func (f *Field) GetArraySize(value data.Map) int {
 	if !f.HasArrayComponent {
 		return 0
@@ -151,30 +278,21 @@ func (f *Field) GetArraySize(value data.Map) int {
 
 //NewField return a new Field for provided expression.
 func NewField(expression string) *Field {
-	var parsedExpression = expression
-	isRoot := strings.HasPrefix(parsedExpression, "/")
-	if isRoot {
-		parsedExpression = string(parsedExpression[1:])
-	}
+	var header = ast.ParseField(expression)
+	isRoot, isVirtual := header.IsRoot, header.IsVirtual
+	parsedExpression, defaultValue := splitDefault(header.Name)
 
-	isVirtual := strings.HasPrefix(parsedExpression, ":")
-	if isVirtual {
-		parsedExpression = string(parsedExpression[1:])
-	}
-
-	isArray := strings.HasPrefix(parsedExpression, "[]")
-	if isArray {
+	var dimensions = 0
+	for strings.HasPrefix(parsedExpression, "[]") {
 		parsedExpression = string(parsedExpression[2:])
+		dimensions++
 	}
-	runes := []rune(parsedExpression)
-	if unicode.IsLower(runes[0]) {
-		isVirtual = true
-	}
-
+	isArray := dimensions > 0
 	var result = &Field{
 		expression:        expression,
 		HasArrayComponent: isArray || strings.Contains(parsedExpression, "[]"),
 		IsArray:           isArray,
+		Dimensions:        dimensions,
 		HasSubPath:        strings.Contains(parsedExpression, "."),
 		Field:             parsedExpression,
 		IsRoot:            isRoot,
@@ -194,8 +312,27 @@ func NewField(expression string) *Field {
 		result.Leaf = result.Child.Leaf
 
 	} else {
+		if len(result.Field) > len(textFieldSuffix) && strings.HasSuffix(result.Field, textFieldSuffix) {
+			result.IsText = true
+			result.Field = string(result.Field[:len(result.Field)-len(textFieldSuffix)])
+		}
+		if len(result.Field) > 1 && strings.HasSuffix(result.Field, requiredFieldSuffix) {
+			result.IsRequired = true
+			result.Field = string(result.Field[:len(result.Field)-1])
+		}
+		for _, operator := range fieldOperators {
+			if len(result.Field) > 1 && strings.HasSuffix(result.Field, operator) {
+				result.Operator = operator
+				result.Field = string(result.Field[:len(result.Field)-1])
+				break
+			}
+		}
 		result.Leaf = result
 	}
+	if defaultValue != nil {
+		result.Leaf.HasDefault = true
+		result.Leaf.Default = *defaultValue
+	}
 	return result
 }
 
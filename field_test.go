@@ -83,3 +83,74 @@ func TestFieldExpression_Set(t *testing.T) {
 	}
 
 }
+
+func TestField_Operator(t *testing.T) {
+	{
+		var object = data.NewMap()
+		field := neatly.NewField("Config.Labels+")
+		assert.Equal(t, neatly.AppendOperator, field.Leaf.Operator)
+		assert.Equal(t, "Labels", field.Leaf.Field)
+		assert.Nil(t, field.SetValue("a", object))
+		assert.Nil(t, field.SetValue([]interface{}{"b", "c"}, object))
+		config := object.GetMap("Config")
+		assert.EqualValues(t, 3, len(*config.GetCollection("Labels")))
+	}
+	{
+		var object = data.NewMap()
+		field := neatly.NewField("Labels=")
+		assert.Nil(t, field.SetValue([]interface{}{"a"}, object))
+		assert.Nil(t, field.SetValue([]interface{}{"b"}, object))
+		assert.EqualValues(t, []interface{}{"b"}, object.Get("Labels"))
+	}
+	{
+		var object = data.NewMap()
+		field := neatly.NewField(":Config&")
+		assert.True(t, field.IsVirtual)
+		assert.Nil(t, field.SetValue(map[string]interface{}{"A": map[string]interface{}{"X": 1}}, object))
+		assert.Nil(t, field.SetValue(map[string]interface{}{"A": map[string]interface{}{"Y": 2}}, object))
+		assert.EqualValues(t, map[string]interface{}{"A": map[string]interface{}{"X": 1, "Y": 2}}, object.Get("Config"))
+	}
+	{
+		var object = data.NewMap()
+		field := neatly.NewField("/Items!")
+		assert.True(t, field.IsRoot)
+		assert.Nil(t, field.SetValue(1, object))
+		assert.NotNil(t, field.SetValue(2, object))
+		assert.False(t, field.IsRequired)
+	}
+	{
+		field := neatly.NewField("Name*")
+		assert.Equal(t, "Name", field.Field)
+		assert.True(t, field.IsRequired)
+		assert.Equal(t, "", field.Operator)
+	}
+	{
+		field := neatly.NewField(`a\=b`)
+		assert.Equal(t, "a=b", field.Field)
+		assert.False(t, field.HasDefault)
+		field = neatly.NewField("Expr=a b")
+		assert.Equal(t, "Expr=a b", field.Field)
+		assert.False(t, field.HasDefault)
+	}
+	{
+		field := neatly.NewField("[]Ports.Timeout=1.5")
+		assert.Equal(t, "Ports", field.Field)
+		assert.Equal(t, "Timeout", field.Leaf.Field)
+		assert.True(t, field.Leaf.HasDefault)
+		assert.Equal(t, "1.5", field.Leaf.Default)
+		assert.Equal(t, "", field.Leaf.Operator)
+	}
+}
+
+func TestField_Dimensions(t *testing.T) {
+	var object = data.NewMap()
+	field := neatly.NewField("Rows.[][]Cells")
+	assert.Equal(t, 2, field.Child.Dimensions)
+	assert.Equal(t, "Cells", field.Child.Field)
+	assert.Equal(t, 1, field.ArrayDepth())
+	assert.Nil(t, field.SetValue([]interface{}{1, 2}, object))
+	assert.Nil(t, field.SetValue(3, object, 1))
+	assert.Equal(t, 2, field.GetArraySize(object))
+	rows := object.GetMap("Rows")
+	assert.EqualValues(t, []interface{}{3}, (*rows.GetCollection("Cells"))[1])
+}
@@ -1,6 +1,6 @@
 module github.com/viant/neatly
 
-go 1.17
+go 1.18
 
 require (
 	github.com/gomarkdown/markdown v0.0.0-20220310201231-552c6011c0b8
@@ -3,7 +3,11 @@ package neatly
 import (
 	"fmt"
 	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 func unescapeSpecialCharacters(input string) (string, bool) {
@@ -36,6 +40,9 @@ func unescapeSpecialCharacters(input string) (string, bool) {
 		result = string(result[:len(result)-2]) + "}"
 		unescaped = true
 	}
+	if !unescaped && strings.Contains(input, "\n") {
+		return input, unescaped //preserve multi-line text indentation
+	}
 	return result, unescaped
 }
 
@@ -86,3 +93,177 @@ func getAssetURIs(value string) []string {
 	}
 	return result
 }
+
+//cloneValue returns deep copy of supplied maps and slices, other values are returned as is
+func cloneValue(source interface{}) interface{} {
+	switch value := source.(type) {
+	case data.Map:
+		return data.Map(cloneValue(map[string]interface{}(value)).(map[string]interface{}))
+	case map[string]interface{}:
+		var result = make(map[string]interface{})
+		for k, v := range value {
+			result[k] = cloneValue(v)
+		}
+		return result
+	case *data.Collection:
+		var result = data.NewCollection()
+		for _, item := range *value {
+			result.Push(cloneValue(item))
+		}
+		return result
+	case []interface{}:
+		var result = make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = cloneValue(item)
+		}
+		return result
+	}
+	return source
+}
+
+//splitArguments splits comma separated arguments, commas within quotes, brackets or parenthesis are preserved
+func splitArguments(text string) []string {
+	var result = make([]string, 0)
+	var depth = 0
+	var quote rune
+	var begin = 0
+	appendArgument := func(end int) {
+		if argument := strings.TrimSpace(text[begin:end]); argument != "" {
+			result = append(result, argument)
+		}
+	}
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			appendArgument(i)
+			begin = i + 1
+		}
+	}
+	appendArgument(len(text))
+	return result
+}
+
+//unquote removes enclosing double or single quotes
+func unquote(text string) string {
+	if len(text) < 2 {
+		return text
+	}
+	if (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
+
+const (
+	nullToken        = "$null"
+	emptyToken       = "$empty"
+	emptyArrayToken  = "$emptyArray"
+	emptyObjectToken = "$emptyObject"
+)
+
+//explicitValue represents explicitly empty value, it is preserved by empty elements removal and resolved once document is loaded
+type explicitValue struct {
+	token string
+}
+
+//Value returns actual value for the explicit value token
+func (v *explicitValue) Value() interface{} {
+	switch v.token {
+	case emptyToken:
+		return ""
+	case emptyArrayToken:
+		return []interface{}{}
+	case emptyObjectToken:
+		return map[string]interface{}{}
+	}
+	return nil
+}
+
+var explicitValues = map[string]*explicitValue{
+	nullToken:        {token: nullToken},
+	emptyToken:       {token: emptyToken},
+	emptyArrayToken:  {token: emptyArrayToken},
+	emptyObjectToken: {token: emptyObjectToken},
+}
+
+//resolveExplicitValues replaces explicit value placeholders with actual values
+func resolveExplicitValues(source interface{}) interface{} {
+	switch value := source.(type) {
+	case *explicitValue:
+		return value.Value()
+	case data.Map:
+		for k, v := range value {
+			value[k] = resolveExplicitValues(v)
+		}
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = resolveExplicitValues(v)
+		}
+	case *data.Collection:
+		for i, item := range *value {
+			(*value)[i] = resolveExplicitValues(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = resolveExplicitValues(item)
+		}
+	}
+	return source
+}
+
+//inferType converts numeric, boolean and RFC3339 time text values to int64, float64, bool and time.Time, single quoted text is unquoted and kept as text
+func inferType(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return value
+	}
+	if len(text) > 1 && strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") {
+		return string(text[1 : len(text)-1])
+	}
+	switch text {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if isNumeric(text) {
+		if intValue, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return intValue
+		}
+		if floatValue, err := strconv.ParseFloat(text, 64); err == nil {
+			return floatValue
+		}
+		return value
+	}
+	if timeValue, err := time.Parse(time.RFC3339, text); err == nil {
+		return timeValue
+	}
+	return value
+}
+
+//isNumeric returns true if text is a decimal number, numbers with leading zeros (i.e. zip codes) are not considered numeric
+func isNumeric(text string) bool {
+	digits := strings.TrimPrefix(text, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return false
+	}
+	for _, r := range digits {
+		if !(unicode.IsDigit(r) || r == '.' || r == 'e' || r == 'E' || r == '-' || r == '+') {
+			return false
+		}
+	}
+	return true
+}
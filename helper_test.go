@@ -47,3 +47,32 @@ func Test_getAssetURIs(t *testing.T) {
 	assert.EqualValues(t, []string{"a", "{c}", "z"}, getAssetURIs("a |{c} | z "))
 
 }
+
+func Test_splitArguments(t *testing.T) {
+	assert.EqualValues(t, []string{"a", "b=1"}, splitArguments("a, b=1"))
+	assert.EqualValues(t, []string{"a", `b="1,2"`}, splitArguments(`a, b="1,2"`))
+	assert.EqualValues(t, []string{"$Len([1,2])", "{\"a\":1,\"b\":2}"}, splitArguments(`$Len([1,2]), {"a":1,"b":2}`))
+	assert.EqualValues(t, []string{}, splitArguments(" "))
+}
+
+func Test_inferType(t *testing.T) {
+	var useCases = []struct {
+		input    interface{}
+		expected interface{}
+	}{
+		{"12", int64(12)},
+		{"-3.5", -3.5},
+		{"1e3", float64(1000)},
+		{"007", "007"},
+		{"0.5", 0.5},
+		{"true", true},
+		{"False", "False"},
+		{"'12'", "12"},
+		{"1-2", "1-2"},
+		{"abc", "abc"},
+		{3, 3},
+	}
+	for _, useCase := range useCases {
+		assert.EqualValues(t, useCase.expected, inferType(useCase.input), toolbox.AsString(useCase.input))
+	}
+}
@@ -0,0 +1,144 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/url"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+//Metadata represents tag object origin
+type Metadata struct {
+	Tag       string `json:",omitempty"`
+	TagID     string `json:",omitempty"`
+	TagIndex  string `json:",omitempty"`
+	Subpath   string `json:",omitempty"`
+	PathMatch string `json:",omitempty"`
+	SourceURL string `json:",omitempty"`
+	Line      int    `json:",omitempty"`
+}
+
+//LoadResult represents loaded document data with metadata keyed by JSON path, i.e. $.Users[1]
+type LoadResult struct {
+	Data      map[string]interface{}
+	Metadata  map[string]*Metadata
+	SourceMap SourceMap `json:",omitempty"` //value positions, built if source map is enabled with Dao.SetSourceMap
+}
+
+//Paths returns sorted metadata JSON paths
+func (r *LoadResult) Paths() []string {
+	var result = make([]string, 0, len(r.Metadata))
+	for path := range r.Metadata {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+//LoadWithMetadata loads document data with tag objects metadata and optionally source map, unlike Load with includeMeta, the data is not modified with meta keys
+func (d *Dao) LoadWithMetadata(context data.Map, source *url.Resource) (*LoadResult, error) {
+	var dao = *d
+	dao.includeMeta = false
+	document, err := dao.loadDocument(context, source, []string{source.URL})
+	if err != nil {
+		return nil, err
+	}
+	var result = &LoadResult{
+		Data:     resolveExplicitValues(map[string]interface{}(document.rootObject)).(map[string]interface{}),
+		Metadata: make(map[string]*Metadata),
+	}
+	collectMetadata(result.Data, document.metadata, result.Metadata)
+	if _, has := result.Metadata["$"]; !has {
+		result.Metadata["$"] = &Metadata{SourceURL: source.URL}
+	}
+	if document.positions != nil {
+		result.SourceMap = buildSourceMap(result.Data, document.positions, document.metadata)
+	}
+	return result, nil
+}
+
+//newMetadata returns metadata for the current tag object
+func (t *Tag) newMetadata(context *tagContext) *Metadata {
+	var result = &Metadata{
+		Tag:       t.Name,
+		TagID:     t.TagID(),
+		Subpath:   t.Subpath,
+		PathMatch: t.PathMatch,
+		SourceURL: context.source.URL,
+		Line:      context.lineNumber,
+	}
+	if t.HasActiveIterator() {
+		result.TagIndex = t.Iterator.Index()
+	}
+	return result
+}
+
+//objectID returns map identity
+func objectID(aMap map[string]interface{}) uintptr {
+	return reflect.ValueOf(aMap).Pointer()
+}
+
+//objectEntry represents object side data, it holds the object reference so that the object address can not be reused
+//by another map while the entry is registered
+type objectEntry[T any] struct {
+	object map[string]interface{}
+	value  T
+}
+
+//objectRegistry represents objects side data keyed by object identity
+type objectRegistry[T any] map[uintptr]*objectEntry[T]
+
+//get returns value registered for the object
+func (r objectRegistry[T]) get(object map[string]interface{}) (T, bool) {
+	entry, ok := r[objectID(object)]
+	if !ok {
+		var result T
+		return result, false
+	}
+	return entry.value, true
+}
+
+//put registers value for the object
+func (r objectRegistry[T]) put(object map[string]interface{}, value T) {
+	r[objectID(object)] = &objectEntry[T]{object: object, value: value}
+}
+
+//collectMetadata collects registered tag objects metadata by JSON path
+func collectMetadata(document map[string]interface{}, registry objectRegistry[*Metadata], result map[string]*Metadata) {
+	walkObjects(document, "$", func(path string, object map[string]interface{}) {
+		if metadata, has := registry.get(object); has {
+			result[path] = metadata
+		}
+	})
+}
+
+//walkObjects calls handler for every object within supplied value with the object JSON path
+func walkObjects(value interface{}, path string, handler func(path string, object map[string]interface{})) {
+	switch actual := value.(type) {
+	case data.Map:
+		walkObjects(map[string]interface{}(actual), path, handler)
+	case map[string]interface{}:
+		handler(path, actual)
+		for k, v := range actual {
+			walkObjects(v, jsonPath(path, k), handler)
+		}
+	case *data.Collection:
+		walkObjects([]interface{}(*actual), path, handler)
+	case []interface{}:
+		for i, item := range actual {
+			walkObjects(item, fmt.Sprintf("%v[%v]", path, i), handler)
+		}
+	}
+}
+
+var jsonPathIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//jsonPath returns JSON path for the key, keys that are not identifiers use bracket notation
+func jsonPath(parent, key string) string {
+	if jsonPathIdentifier.MatchString(key) {
+		return parent + "." + key
+	}
+	return fmt.Sprintf("%v['%v']", parent, key)
+}
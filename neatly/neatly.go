@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/viant/neatly"
 	"github.com/viant/toolbox"
@@ -21,10 +22,12 @@ func init() {
 	flag.String("f", "json", "<output format> json or yaml")
 	flag.Bool("v", false, "neatly version")
 	flag.Bool("m", false, "include neatly meta data")
+	flag.Bool("t", false, "infer scalar types")
+	flag.String("x", "", "<external udfs config path> JSON or YAML list of external process udfs")
 
 }
 
-func printJSON(aMap map[string]interface{}) {
+func printJSON(aMap neatly.OrderedMap) {
 	buf, err := json.MarshalIndent(aMap, "", "\t")
 	if err != nil {
 		log.Fatal("failed to build JSON")
@@ -32,16 +35,40 @@ func printJSON(aMap map[string]interface{}) {
 	fmt.Printf("%s\n", buf)
 }
 
-func printYAML(aMap map[string]interface{}) {
+func printYAML(aMap neatly.OrderedMap) {
 	buf, err := yaml.Marshal(aMap)
 	if err != nil {
-		log.Fatal("failed to build JSON")
+		log.Fatal("failed to build YAML")
 	}
 	fmt.Printf("%s\n", buf)
 }
 
+func printUdfs(dao *neatly.Dao) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "CATEGORY\tFUNCTION\tRETURNS\tDESCRIPTION")
+	for _, udf := range append(neatly.StandardUdfs().Udfs(), dao.ExternalUdfs().Udfs()...) {
+		fmt.Fprintf(writer, "%v\t$%v(%v)\t%v\t%v\n", udf.Category, udf.Name, strings.Join(udf.Args, ", "), udf.Returns, udf.Description)
+	}
+	writer.Flush()
+}
+
 func main() {
+	flag.CommandLine.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: neatly [flags]\n       neatly udfs\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	dao := neatly.NewDao(toolbox.AsBoolean(flag.Lookup("m").Value.String()), "", "", "", nil)
+	defer dao.Close()
+	if externalUdfs := flag.Lookup("x").Value.String(); externalUdfs != "" {
+		if err := dao.LoadExternalUdfs(url.NewResource(externalUdfs)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if flag.Arg(0) == "udfs" {
+		printUdfs(dao)
+		return
+	}
 	flagset := make(map[string]string)
 	flag.Visit(func(f *flag.Flag) {
 		flagset[f.Name] = f.Value.String()
@@ -59,11 +86,12 @@ func main() {
 		return
 	}
 	var context = data.NewMap()
-	var neatlyDocument = make(map[string]interface{})
-	dao := neatly.NewDao(toolbox.AsBoolean(flag.Lookup("m").Value.String()), "", "", "", nil)
+	var neatlyDocument = neatly.OrderedMap{}
+	dao.SetTypeInference(toolbox.AsBoolean(flag.Lookup("t").Value.String()))
 	err := dao.Load(context, url.NewResource(input), &neatlyDocument)
 	if err != nil {
-		log.Fatal("failed to load neatly document: %v %v\n", input, err)
+		dao.Close()
+		log.Fatalf("failed to load neatly document: %v %v\n", input, err)
 	}
 	switch strings.ToLower(flag.Lookup("f").Value.String()) {
 	case "json":
@@ -0,0 +1,221 @@
+package neatly
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"gopkg.in/yaml.v2"
+	"sort"
+)
+
+//MapItem represents an ordered map entry
+type MapItem struct {
+	Key   string
+	Value interface{}
+}
+
+//OrderedMap represents a map that keeps neatly document header column and tag declaration key order, use it as Load target to preserve key order
+type OrderedMap []MapItem
+
+//Get returns value for supplied key
+func (m OrderedMap) Get(key string) (interface{}, bool) {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value, true
+		}
+	}
+	return nil, false
+}
+
+//Keys returns map keys in order
+func (m OrderedMap) Keys() []string {
+	var result = make([]string, len(m))
+	for i, item := range m {
+		result[i] = item.Key
+	}
+	return result
+}
+
+//MarshalJSON encodes map as JSON object with keys in order
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf = new(bytes.Buffer)
+	buf.WriteString("{")
+	for i, item := range m {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		value, err := json.Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+//MarshalYAML returns yaml.MapSlice with keys in order
+func (m OrderedMap) MarshalYAML() (interface{}, error) {
+	var result = make(yaml.MapSlice, len(m))
+	for i, item := range m {
+		result[i] = yaml.MapItem{Key: item.Key, Value: item.Value}
+	}
+	return result, nil
+}
+
+//keyOrder represents document keys order, each key is ranked by its first occurrence in headers and tag declarations,
+//it orders keys that were not set from an object header
+type keyOrder map[string]int
+
+//add adds keys that have not been ranked yet
+func (o keyOrder) add(keys ...string) {
+	for _, key := range keys {
+		if _, has := o[key]; !has {
+			o[key] = len(o)
+		}
+	}
+}
+
+//addColumns adds header column field names
+func (o keyOrder) addColumns(columns []string) {
+	for _, column := range columns {
+		if column == "" || isExtendsColumn(column) {
+			continue
+		}
+		for field := NewField(column); field != nil; field = field.Child {
+			if field.IsVirtual {
+				break
+			}
+			o.add(field.Field)
+		}
+	}
+}
+
+//merge adds keys from supplied order
+func (o keyOrder) merge(order keyOrder) {
+	var keys = make([]string, len(order))
+	for key, rank := range order {
+		keys[rank] = key
+	}
+	o.add(keys...)
+}
+
+//sortKeys sorts keys by rank, keys without rank are placed at the end in alphabetical order
+func (o keyOrder) sortKeys(keys []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		iRank, iHas := o[keys[i]]
+		jRank, jHas := o[keys[j]]
+		if iHas && jHas {
+			return iRank < jRank
+		}
+		if iHas != jHas {
+			return iHas
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+//objectKeyOrder represents header order of the keys set in each loaded object keyed by object identity
+type objectKeyOrder objectRegistry[[]string]
+
+//add appends key to the object keys unless it has been already added
+func (o objectKeyOrder) add(object map[string]interface{}, key string) {
+	registry := objectRegistry[[]string](o)
+	keys, _ := registry.get(object)
+	for _, candidate := range keys {
+		if candidate == key {
+			return
+		}
+	}
+	registry.put(object, append(keys, key))
+}
+
+//record adds field path keys to the target object and its nested objects keys, indexes select array elements
+func (o objectKeyOrder) record(field *Field, target data.Map, indexes ...int) {
+	var object = map[string]interface{}(target)
+	for ; field != nil; field = field.Child {
+		o.add(object, field.Field)
+		value := object[field.Field]
+		if field.IsArray {
+			var index int
+			index, indexes = shiftIndex(indexes...)
+			items := toolbox.AsSlice(value)
+			if value == nil || index >= len(items) {
+				return
+			}
+			value = items[index]
+		}
+		if !field.HasSubPath || field.Child.IsIndex {
+			return
+		}
+		var ok bool
+		if object, ok = asStringKeyMap(value); !ok {
+			return
+		}
+	}
+}
+
+//merge adds keys from supplied order
+func (o objectKeyOrder) merge(order objectKeyOrder) {
+	for id, entry := range order {
+		if _, has := o[id]; !has {
+			o[id] = entry
+		}
+	}
+}
+
+//newOrderedValue converts maps within supplied value into OrderedMap
+func newOrderedValue(value interface{}, order keyOrder, objects objectKeyOrder) interface{} {
+	switch actual := value.(type) {
+	case data.Map:
+		return newOrderedMap(actual, order, objects)
+	case map[string]interface{}:
+		return newOrderedMap(actual, order, objects)
+	case *data.Collection:
+		return newOrderedValue([]interface{}(*actual), order, objects)
+	case []interface{}:
+		var result = make([]interface{}, len(actual))
+		for i, item := range actual {
+			result[i] = newOrderedValue(item, order, objects)
+		}
+		return result
+	}
+	if toolbox.IsMap(value) {
+		return newOrderedMap(toolbox.AsMap(value), order, objects)
+	}
+	return value
+}
+
+//newOrderedMap returns ordered map with keys in the object header order, keys not set from the object header, i.e. JSON value keys
+//are placed at the end in document order
+func newOrderedMap(aMap map[string]interface{}, order keyOrder, objects objectKeyOrder) OrderedMap {
+	var keys = make([]string, 0, len(aMap))
+	var recorded = make(map[string]bool)
+	headerKeys, _ := objectRegistry[[]string](objects).get(aMap)
+	for _, key := range headerKeys {
+		if _, has := aMap[key]; has && !recorded[key] {
+			keys = append(keys, key)
+			recorded[key] = true
+		}
+	}
+	var remaining = make([]string, 0, len(aMap)-len(keys))
+	for key := range aMap {
+		if !recorded[key] {
+			remaining = append(remaining, key)
+		}
+	}
+	order.sortKeys(remaining)
+	keys = append(keys, remaining...)
+	var result = make(OrderedMap, len(keys))
+	for i, key := range keys {
+		result[i] = MapItem{Key: key, Value: newOrderedValue(aMap[key], order, objects)}
+	}
+	return result
+}
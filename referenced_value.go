@@ -27,9 +27,9 @@ func (v *referenceValues) Add(tagName string, field *Field, object data.Map) err
 		Field:  field,
 		Object: object,
 	}
-	referencedValue.Setter = func(value interface{}) {
+	referencedValue.Setter = func(value interface{}) error {
 		referencedValue.Used = true
-		field.Set(value, referencedValue.Object)
+		return field.SetValue(value, referencedValue.Object)
 	}
 	(*v)[tagName] = referencedValue
 	return nil
@@ -46,8 +46,7 @@ func (v *referenceValues) Apply(tagName string, value interface{}) error {
 
 		return fmt.Errorf("Missing referenceValue %v in the previous rows, available[%v]", tagName, strings.Join(referencesSoFar, ","))
 	}
-	referencedValue.Setter(value)
-	return nil
+	return referencedValue.Setter(value)
 }
 
 func newReferenceValues() referenceValues {
@@ -57,7 +56,7 @@ func newReferenceValues() referenceValues {
 
 //referenceValue represent reference value
 type referenceValue struct {
-	Setter func(value interface{}) //setter handler
+	Setter func(value interface{}) error //setter handler
 	Key    string                  //reference key
 	Field  *Field                  //field
 	Object data.Map                //target object
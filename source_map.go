@@ -0,0 +1,170 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//Position represents value origin in a neatly document, positions are recorded only for the loaded document cells:
+//values of an @asset or a nested $LoadNeatly document report the referencing cell position with the direct asset URLs in Assets,
+//not their own URL and line
+type Position struct {
+	URL    string
+	Line   int      `json:",omitempty"`
+	Column int      `json:",omitempty"`
+	TagID  string   `json:",omitempty"`
+	Assets []string `json:",omitempty"` //assets the value was loaded from, i.e. @asset or $LoadNeatly document
+}
+
+//SourceMap represents output JSON path to document position map
+type SourceMap map[string]*Position
+
+//Paths returns sorted source map JSON paths
+func (m SourceMap) Paths() []string {
+	var result = make([]string, 0, len(m))
+	for path := range m {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+//Position returns position of the value for supplied JSON path, if the value position has not been recorded,
+//the closest parent path position is returned, i.e. value loaded from an @asset
+func (m SourceMap) Position(path string) (*Position, bool) {
+	for {
+		if position, has := m[path]; has {
+			return position, true
+		}
+		index := strings.LastIndexAny(path, ".[")
+		if index <= 0 {
+			return nil, false
+		}
+		path = string(path[:index])
+	}
+}
+
+//positions represents value positions registry, keyed by holding object identity and value JSON path suffix
+type positions objectRegistry[map[string]*Position]
+
+//add adds value position for the field value within the target object
+func (p positions) add(field *Field, target data.Map, position *Position, indexes ...int) {
+	object, key := field.location(target, indexes...)
+	if object == nil {
+		return
+	}
+	registry := objectRegistry[map[string]*Position](p)
+	values, has := registry.get(object)
+	if !has {
+		values = make(map[string]*Position)
+		registry.put(object, values)
+	}
+	values[key] = position
+}
+
+var loadNeatlyExpression = regexp.MustCompile(`\$LoadNeatly\(([^)]+)\)`)
+
+//valueAssets returns assets referenced by the cell value
+func valueAssets(value string) []string {
+	var result = make([]string, 0)
+	if isExternalResource(value) {
+		for _, asset := range getAssetURIs(value) {
+			result = append(result, strings.TrimLeft(asset, "@#"))
+		}
+	}
+	for _, match := range loadNeatlyExpression.FindAllStringSubmatch(value, -1) {
+		result = append(result, strings.Trim(strings.TrimSpace(match[1]), `"'`))
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+//location returns the object holding the field value and the value JSON path suffix
+func (f *Field) location(target data.Map, indexes ...int) (data.Map, string) {
+	var index int
+	if f.IsArray {
+		index, indexes = shiftIndex(indexes...)
+	}
+	var key = jsonPath("", f.Field)
+	if !f.HasSubPath {
+		if f.IsArray {
+			key += fmt.Sprintf("[%v]", index)
+		}
+		return target, key
+	}
+	if f.Child.IsIndex {
+		return target, fmt.Sprintf("%v[%v]", key, f.Child.Field)
+	}
+	var value = target.Get(f.Field)
+	if f.IsArray {
+		items := toolbox.AsSlice(value)
+		if index >= len(items) {
+			return nil, ""
+		}
+		value = items[index]
+	}
+	switch object := value.(type) {
+	case data.Map:
+		return f.Child.location(object, indexes...)
+	case map[string]interface{}:
+		return f.Child.location(object, indexes...)
+	}
+	return nil, ""
+}
+
+//recordPosition records field key order in the target object and field value position if source map is enabled
+func (d *Dao) recordPosition(context *tagContext, field *Field, target data.Map, line, column int, value string, indexes ...int) {
+	if !field.IsVirtual {
+		context.objectKeys.record(field, target, indexes...)
+	}
+	if context.positions == nil || field.IsVirtual || value == "" {
+		return
+	}
+	var position = &Position{
+		URL:    context.source.URL,
+		Line:   line,
+		Column: column,
+		TagID:  context.tag.TagID(),
+		Assets: valueAssets(value),
+	}
+	context.positions.add(field, target, position, indexes...)
+}
+
+//columnPosition returns 1 based column position
+func columnPosition(columns []string, column string) int {
+	for i, candidate := range columns {
+		if candidate == column {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+//rowLine returns source line number for the row index
+func (c *tagContext) rowLine(index int) int {
+	if index < len(c.lineNumbers) {
+		return c.lineNumbers[index]
+	}
+	return c.lineNumber
+}
+
+//buildSourceMap builds source map for loaded document data
+func buildSourceMap(document map[string]interface{}, registry positions, metadata objectRegistry[*Metadata]) SourceMap {
+	var result = make(SourceMap)
+	walkObjects(document, "$", func(path string, object map[string]interface{}) {
+		if meta, has := metadata.get(object); has {
+			result[path] = &Position{URL: meta.SourceURL, Line: meta.Line, TagID: meta.TagID}
+		}
+		values, _ := objectRegistry[map[string]*Position](registry).get(object)
+		for key, position := range values {
+			result[path+key] = position
+		}
+	})
+	return result
+}
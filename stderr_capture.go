@@ -0,0 +1,62 @@
+//go:build !windows
+
+package neatly
+
+import (
+	"os"
+	"syscall"
+)
+
+//readStderr reads stderr pipe until it is closed, each non blocking read is done while holding the capture mutex,
+//so that start can discard output pending in the pipe
+func readStderr(c *stderrCapture) {
+	raw, err := c.reader.SyscallConn()
+	if err != nil {
+		return
+	}
+	var buffer = make([]byte, 4096)
+	for {
+		var read int
+		var readErr error
+		err = raw.Read(func(fd uintptr) bool {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			read, readErr = syscall.Read(int(fd), buffer)
+			if readErr == syscall.EAGAIN {
+				return false
+			}
+			if read > 0 {
+				c.write(buffer[:read])
+			}
+			return true
+		})
+		if readErr == syscall.EINTR {
+			continue
+		}
+		if err != nil || readErr != nil || read <= 0 {
+			return
+		}
+	}
+}
+
+//discardPending discards output written to the pipe but not read yet, at most maxStderrCapture bytes are discarded
+//so that a process writing continuously does not block the request
+func discardPending(reader *os.File) {
+	raw, err := reader.SyscallConn()
+	if err != nil {
+		return
+	}
+	var buffer = make([]byte, 4096)
+	_ = raw.Control(func(fd uintptr) {
+		for discarded := 0; discarded < maxStderrCapture; {
+			read, err := syscall.Read(int(fd), buffer)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil || read <= 0 {
+				return
+			}
+			discarded += read
+		}
+	})
+}
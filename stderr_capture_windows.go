@@ -0,0 +1,23 @@
+package neatly
+
+import "os"
+
+//readStderr reads stderr pipe until it is closed, windows pipes are blocking so the read is done outside the capture mutex
+func readStderr(c *stderrCapture) {
+	var buffer = make([]byte, 4096)
+	for {
+		read, err := c.reader.Read(buffer)
+		if read > 0 {
+			c.mutex.Lock()
+			c.write(buffer[:read])
+			c.mutex.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+//discardPending is a no-op on windows, a blocking pipe can not be read without waiting for output
+func discardPending(reader *os.File) {
+}
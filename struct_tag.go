@@ -0,0 +1,225 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox"
+	"reflect"
+	"strings"
+)
+
+const (
+	//structTagName represents neatly struct tag name, i.e. `neatly:"name,required,default=8080,inline"`
+	structTagName    = "neatly"
+	requiredOption   = "required"
+	inlineOption     = "inline"
+	defaultOptionKey = "default="
+)
+
+//structField represents decodable struct field
+type structField struct {
+	name         string
+	index        []int
+	required     bool
+	defaultValue *string
+}
+
+type structFieldSet []*structField
+
+//lookup returns field matching the key, exact match takes precedence over case insensitive one
+func (s structFieldSet) lookup(key string) (*structField, bool) {
+	for _, field := range s {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range s {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+//structTag represents parsed neatly struct tag
+type structTag struct {
+	name         string
+	required     bool
+	inline       bool
+	defaultValue *string
+}
+
+//parseStructTag parses neatly struct tag, default option takes the rest of the tag, so it has to be the last option if the value contains a comma
+func parseStructTag(tag string) *structTag {
+	var result = &structTag{}
+	options := strings.Split(tag, ",")
+	result.name = strings.TrimSpace(options[0])
+	for i := 1; i < len(options); i++ {
+		option := strings.TrimSpace(options[i])
+		switch {
+		case option == requiredOption:
+			result.required = true
+		case option == inlineOption:
+			result.inline = true
+		case strings.HasPrefix(option, defaultOptionKey):
+			value := strings.Join(options[i:], ",")
+			value = string(value[strings.Index(value, defaultOptionKey)+len(defaultOptionKey):])
+			result.defaultValue = &value
+			i = len(options)
+		}
+	}
+	return result
+}
+
+//structFields returns exported struct fields, fields of embedded structs without name and inline struct fields are promoted
+func structFields(structType reflect.Type) structFieldSet {
+	var result = make(structFieldSet, 0)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		var tag = &structTag{}
+		if value, ok := field.Tag.Lookup(structTagName); ok {
+			tag = parseStructTag(value)
+		} else if value, ok := field.Tag.Lookup("json"); ok {
+			tag.name = strings.Split(value, ",")[0]
+		}
+		if tag.name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct && (tag.inline || (field.Anonymous && tag.name == "")) {
+			for _, embedded := range structFields(fieldType) {
+				result = append(result, &structField{name: embedded.name, index: append([]int{i}, embedded.index...), required: embedded.required, defaultValue: embedded.defaultValue})
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		var name = tag.name
+		if name == "" {
+			name = field.Name
+		}
+		result = append(result, &structField{name: name, index: []int{i}, required: tag.required, defaultValue: tag.defaultValue})
+	}
+	return result
+}
+
+//missingFieldError returns required field error pointing at the tag that should have supplied the value
+func missingFieldError(sourceMap SourceMap, path, name string) error {
+	position, _ := sourceMap.Position(path)
+	var message = fmt.Sprintf("missing required field %v", name)
+	if position != nil && position.TagID != "" {
+		message += fmt.Sprintf(" in %v tag", position.TagID)
+	}
+	return &DecodingError{Path: jsonPath(path, name), Position: position, Message: message}
+}
+
+//structMapper maps loaded value keys to target struct field names, so that converter can assign it, it also sets defaults and checks required fields,
+//source map used to report missing field position is built only on error
+type structMapper struct {
+	sourceMap func() SourceMap
+}
+
+//hasStruct returns true if type is a struct or its element type is a struct at any level
+func hasStruct(targetType reflect.Type) bool {
+	for {
+		switch targetType.Kind() {
+		case reflect.Struct:
+			return true
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			targetType = targetType.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+func (m *structMapper) mapValue(path string, value interface{}, targetType reflect.Type) (interface{}, error) {
+	for targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+	switch targetType.Kind() {
+	case reflect.Struct:
+		if aMap, ok := asStringKeyMap(value); ok {
+			return m.mapStruct(path, aMap, targetType)
+		}
+	case reflect.Slice, reflect.Array:
+		if toolbox.IsSlice(value) {
+			items := toolbox.AsSlice(value)
+			var result = make([]interface{}, len(items))
+			for i, item := range items {
+				var err error
+				if result[i], err = m.mapValue(fmt.Sprintf("%v[%v]", path, i), item, targetType.Elem()); err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		}
+	case reflect.Map:
+		if aMap, ok := asStringKeyMap(value); ok {
+			var result = make(map[string]interface{}, len(aMap))
+			for k, v := range aMap {
+				var err error
+				if result[k], err = m.mapValue(jsonPath(path, k), v, targetType.Elem()); err != nil {
+					return nil, err
+				}
+			}
+			return result, nil
+		}
+	}
+	return value, nil
+}
+
+func (m *structMapper) mapStruct(path string, source map[string]interface{}, structType reflect.Type) (map[string]interface{}, error) {
+	var result = make(map[string]interface{})
+	fields := structFields(structType)
+	var mapped = make(map[*structField]bool)
+	for _, key := range sortedKeys(source) {
+		field, ok := fields.lookup(key)
+		if !ok {
+			result[key] = source[key]
+			continue
+		}
+		mapped[field] = true
+		value, err := m.mapValue(jsonPath(path, key), source[key], structType.FieldByIndex(field.index).Type)
+		if err != nil {
+			return nil, err
+		}
+		setByFieldIndex(result, structType, field.index, value)
+	}
+	for _, field := range fields {
+		if mapped[field] {
+			continue
+		}
+		if field.defaultValue != nil {
+			setByFieldIndex(result, structType, field.index, *field.defaultValue)
+		} else if field.required {
+			return nil, missingFieldError(m.sourceMap(), path, field.name)
+		}
+	}
+	return result, nil
+}
+
+//setByFieldIndex sets value in the map using Go field names, nested maps are created for inline fields, embedded struct fields are left promoted
+func setByFieldIndex(target map[string]interface{}, structType reflect.Type, index []int, value interface{}) {
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	field := structType.Field(index[0])
+	if len(index) == 1 {
+		target[field.Name] = value
+		return
+	}
+	if field.Anonymous {
+		setByFieldIndex(target, field.Type, index[1:], value)
+		return
+	}
+	nested, ok := target[field.Name].(map[string]interface{})
+	if !ok {
+		nested = make(map[string]interface{})
+		target[field.Name] = nested
+	}
+	setByFieldIndex(nested, field.Type, index[1:], value)
+}
@@ -1,6 +1,8 @@
 package neatly
 
 import (
+	"fmt"
+	"github.com/viant/neatly/ast"
 	"github.com/viant/toolbox"
 	"github.com/viant/toolbox/data"
 	"github.com/viant/toolbox/storage"
@@ -10,6 +12,9 @@ import (
 	"unicode"
 )
 
+//maxTagDimensions is the max number of array tag dimensions, each tag header occurrence can only start a new inner array
+const maxTagDimensions = 2
+
 //Tag represents a nearly tag
 type Tag struct {
 	OwnerSource *url.Resource
@@ -17,10 +22,13 @@ type Tag struct {
 	Name        string
 	Group       string
 	IsArray     bool
+	Dimensions  int //number of array dimensions, each [][]Tag header occurrence starts a new inner array
 	Iterator    *TagIterator
 	LineNumber  int
 	Subpath     string
 	PathMatch   string
+	Import      string //imported tag expression (alias.Tag) which rows are merged into this tag
+	KeyField    string //key field of map keyed collection tag, i.e. Id for {Id}Users
 	tagIdPrefix string
 }
 
@@ -36,7 +44,9 @@ func (t *Tag) setTagObject(context *tagContext, record map[string]interface{}, i
 	var result data.Map
 	if t.IsArray {
 		result = data.NewMap()
-		context.objectContainer.GetCollection(t.Name).Push(result)
+		t.collection(context).Push(result)
+	} else if t.KeyField != "" {
+		result = data.NewMap()
 	} else {
 		result = context.objectContainer.GetMap(t.Name)
 	}
@@ -53,6 +63,7 @@ func (t *Tag) setTagObject(context *tagContext, record map[string]interface{}, i
 		context.Subpath = t.Subpath
 	}
 	context.tagID = t.TagID()
+	context.metadata.put(result, t.newMetadata(context))
 
 	if includeMeta {
 		t.setMeta(result, record)
@@ -61,6 +72,36 @@ func (t *Tag) setTagObject(context *tagContext, record map[string]interface{}, i
 	return result
 }
 
+//collection returns array tag collection the tag objects are pushed to, for multi dimension tag it is the last inner array
+func (t *Tag) collection(context *tagContext) *data.Collection {
+	collection := context.objectContainer.GetCollection(t.Name)
+	for i := 1; i < t.Dimensions && len(*collection) > 0; i++ {
+		inner, ok := (*collection)[len(*collection)-1].(*data.Collection)
+		if !ok {
+			break
+		}
+		collection = inner
+	}
+	return collection
+}
+
+//setKeyedObject puts current tag object into map keyed collection under key field value
+func (t *Tag) setKeyedObject(context *tagContext) error {
+	key, has := context.tagObject.GetValue(t.KeyField)
+	if !has {
+		key, has = context.virtualObjects.GetValue(t.KeyField)
+	}
+	if !has || toolbox.AsString(key) == "" {
+		return fmt.Errorf("%v - key field %v was empty", t.TagID(), t.KeyField)
+	}
+	collection := context.objectContainer.GetMap(t.Name)
+	if collection.Has(toolbox.AsString(key)) {
+		return fmt.Errorf("%v - duplicate key %v: %v", t.TagID(), t.KeyField, key)
+	}
+	collection.Put(toolbox.AsString(key), context.tagObject)
+	return nil
+}
+
 func (t *Tag) expandPathIfNeeded(subpath string) (string, string) {
 	if !strings.HasSuffix(subpath, "*") {
 		return subpath, ""
@@ -184,22 +225,20 @@ func (t *Tag) TagID() string {
 
 //NewTag creates a new neatly tag
 func NewTag(ownerName string, ownerSource *url.Resource, key string, lineNumber int) *Tag {
+	var parsed = ast.ParseTag(key)
 	var result = &Tag{
 		OwnerName:   ownerName,
 		OwnerSource: ownerSource,
-		Name:        key,
+		Name:        parsed.Name,
+		IsArray:     parsed.IsArray,
+		Dimensions:  parsed.Dimensions,
+		KeyField:    parsed.KeyField,
+		Import:      parsed.Import,
 		LineNumber:  lineNumber,
 	}
-	key = decodeIteratorIfPresent(key, result)
-	if len(key) > 2 && string(key[0:2]) == "[]" {
-		result.Name = string(key[2:])
-		result.IsArray = true
+	if parsed.Iterator != nil {
+		result.Iterator = newTagIterator(parsed.Iterator)
 	}
-
-	if rangeIndex := strings.LastIndex(result.Name, "{"); rangeIndex != -1 {
-		result.Name = string(result.Name[:rangeIndex])
-	}
-
 	if ownerName != "" {
 		ownerName = ownerName + "_"
 	}
@@ -2,8 +2,8 @@ package neatly
 
 import (
 	"fmt"
+	"github.com/viant/neatly/ast"
 	"github.com/viant/toolbox"
-	"strings"
 )
 
 //TagIterator represents tag iterator to produce TagIndex
@@ -30,26 +30,13 @@ func (i *TagIterator) Index() string {
 	return fmt.Sprintf(i.Template, i.index)
 }
 
-func decodeIteratorIfPresent(key string, result *Tag) string {
-	iteratorStartPosition := strings.Index(key, "{")
-	if iteratorStartPosition != -1 {
-		iteratorEndPosition := strings.Index(key, "}")
-		if iteratorEndPosition != -1 {
-			iteratorConstrain := key[iteratorStartPosition+1 : iteratorEndPosition]
-			pair := strings.Split(iteratorConstrain, "..")
-			for i, value := range pair {
-				pair[i] = strings.TrimSpace(value)
-			}
-			if len(pair) == 2 {
-				result.Iterator = &TagIterator{
-					Min:      toolbox.AsInt(pair[0]),
-					Max:      toolbox.AsInt(pair[1]),
-					Template: "%0" + toolbox.AsString(len(pair[1])) + "d",
-				}
-				result.Iterator.index = result.Iterator.Min
-				key = string(key[:iteratorStartPosition])
-			}
-		}
+//newTagIterator creates tag iterator for parsed tag iterator i.e. {1..003}
+func newTagIterator(iterator *ast.Iterator) *TagIterator {
+	var result = &TagIterator{
+		Min:      iterator.Min,
+		Max:      iterator.Max,
+		Template: "%0" + toolbox.AsString(iterator.Width) + "d",
 	}
-	return key
+	result.index = result.Min
+	return result
 }
@@ -16,3 +16,11 @@ func Test_Tag(t *testing.T) {
 	assert.Equal(t, "%03d", tag.Iterator.Template)
 	assert.Equal(t, 1, tag.LineNumber)
 }
+
+func Test_KeyedTag(t *testing.T) {
+	var tag = neatly.NewTag("", url.NewResource("test"), "{Id}Users{1 .. 3}", 1)
+	assert.False(t, tag.IsArray)
+	assert.Equal(t, "Users", tag.Name)
+	assert.Equal(t, "Id", tag.KeyField)
+	assert.Equal(t, 3, tag.Iterator.Max)
+}
@@ -0,0 +1,153 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/neatly/ast"
+	"github.com/viant/toolbox/data"
+	"strings"
+)
+
+const (
+	templateDirective  = "Template "
+	templateCallPrefix = "$Use("
+	templateCallName   = "Use"
+)
+
+//TagTemplate represents a named, parameterized block of rows that can be instantiated with $Use(Name, param=value) expression
+type TagTemplate struct {
+	Name       string
+	Params     []string          //declared parameter names
+	Defaults   map[string]string //parameter default values
+	Header     string            //template header line
+	Rows       []string          //template rows including inline array continuation rows
+	LineNumber int
+	header     *documentLine
+	rows       []*documentLine
+}
+
+//Args returns template arguments for supplied call arguments, it validates that all parameters are supplied
+func (t *TagTemplate) Args(callArgs map[string]string) (data.Map, error) {
+	var result = data.NewMap()
+	for k, v := range t.Defaults {
+		result.Put(k, v)
+	}
+	for k, v := range callArgs {
+		if _, has := t.Defaults[k]; !has && !t.hasParam(k) {
+			return nil, fmt.Errorf("unknown %v template parameter: %v", t.Name, k)
+		}
+		result.Put(k, v)
+	}
+	for _, param := range t.Params {
+		if !result.Has(param) {
+			return nil, fmt.Errorf("missing %v template parameter: %v", t.Name, param)
+		}
+	}
+	return result, nil
+}
+
+func (t *TagTemplate) hasParam(name string) bool {
+	for _, param := range t.Params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+//NewTagTemplate creates a template for supplied declaration i.e. 'Template HttpCheck(url, code=200)'
+func NewTagTemplate(declaration string, lineNumber int) (*TagTemplate, error) {
+	declaration = strings.TrimSpace(strings.Replace(declaration, strings.TrimSpace(templateDirective), "", 1))
+	var result = &TagTemplate{
+		Name:       declaration,
+		Params:     make([]string, 0),
+		Defaults:   make(map[string]string),
+		LineNumber: lineNumber,
+	}
+	if argsIndex := strings.Index(declaration, "("); argsIndex != -1 {
+		if !strings.HasSuffix(declaration, ")") {
+			return nil, fmt.Errorf("invalid template declaration: %v", declaration)
+		}
+		result.Name = strings.TrimSpace(declaration[:argsIndex])
+		for _, param := range splitArguments(declaration[argsIndex+1 : len(declaration)-1]) {
+			if pair := strings.SplitN(param, "=", 2); len(pair) == 2 {
+				result.Defaults[strings.TrimSpace(pair[0])] = unquote(strings.TrimSpace(pair[1]))
+				continue
+			}
+			result.Params = append(result.Params, param)
+		}
+	}
+	if result.Name == "" {
+		return nil, fmt.Errorf("template name was empty: %v", declaration)
+	}
+	return result, nil
+}
+
+//isTemplateCall returns true if value is a template instantiation expression
+func isTemplateCall(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, templateCallPrefix) && strings.HasSuffix(value, ")")
+}
+
+//parseTemplateCall parses $Use(Name, param1=value1, paramN=valueN) expression
+func parseTemplateCall(expression string) (string, map[string]string, error) {
+	expression = strings.TrimSpace(expression)
+	args := splitArguments(expression[len(templateCallPrefix) : len(expression)-1])
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("template name was missing: %v", expression)
+	}
+	var callArgs = make(map[string]string)
+	for _, arg := range args[1:] {
+		pair := strings.SplitN(arg, "=", 2)
+		if len(pair) != 2 {
+			return "", nil, fmt.Errorf("invalid template argument: %v, expected name=value", arg)
+		}
+		callArgs[strings.TrimSpace(pair[0])] = unquote(strings.TrimSpace(pair[1]))
+	}
+	return args[0], callArgs, nil
+}
+
+//documentLines returns document tag header and row lines with their source line numbers, import specs and declared templates
+func documentLines(document *ast.Document) ([]*documentLine, []int, []string, map[string]*TagTemplate, error) {
+	var lines = make([]*documentLine, 0)
+	var lineNumbers = make([]int, 0)
+	var importSpecs = make([]string, 0)
+	var templates = make(map[string]*TagTemplate)
+	var appendLine = func(node ast.Node, line *documentLine) {
+		lines = append(lines, line)
+		lineNumbers = append(lineNumbers, node.Pos().Line)
+	}
+	for _, node := range document.Nodes {
+		switch actual := node.(type) {
+		case *ast.Import:
+			importSpecs = append(importSpecs, actual.Specs...)
+		case *ast.Template:
+			template, err := NewTagTemplate(actual.Declaration, actual.Line)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			template.Header = actual.Raw
+			template.header = newHeaderLine(actual.Declaration, actual.Fields)
+			for _, row := range actual.Rows {
+				template.Rows = append(template.Rows, rowText(row))
+				template.rows = append(template.rows, newRowLine(row))
+			}
+			templates[template.Name] = template
+		case *ast.TagHeader:
+			appendLine(node, newHeaderLine(actual.Tag, actual.Fields))
+		case *ast.DataRow, *ast.ContinuationRow:
+			appendLine(node, newRowLine(node))
+		}
+	}
+	return lines, lineNumbers, importSpecs, templates, nil
+}
+
+//rowText returns data or continuation row text
+func rowText(node ast.Node) string {
+	switch actual := node.(type) {
+	case *ast.DataRow:
+		return actual.Raw
+	case *ast.ContinuationRow:
+		return actual.Raw
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//Epoch time format units
+const (
+	EpochSeconds = "epoch"
+	EpochMillis  = "epochMillis"
+	EpochMicros  = "epochMicros"
+	EpochNanos   = "epochNanos"
+)
+
+//epochUnits maps lower case epoch format to its unit
+var epochUnits = map[string]time.Duration{
+	strings.ToLower(EpochSeconds): time.Second,
+	strings.ToLower(EpochMillis):  time.Millisecond,
+	strings.ToLower(EpochMicros):  time.Microsecond,
+	strings.ToLower(EpochNanos):   time.Nanosecond,
+}
+
+//timeArgs returns udf arguments, a single argument call passes the argument as is
+func timeArgs(source interface{}) []interface{} {
+	if source == nil {
+		return []interface{}{}
+	}
+	if toolbox.IsSlice(source) {
+		return toolbox.AsSlice(source)
+	}
+	if text, ok := source.(string); ok && strings.TrimSpace(text) == "" {
+		return []interface{}{}
+	}
+	return []interface{}{source}
+}
+
+//timeLocation returns location of zone argument at index, or nil if the argument is missing or empty
+func timeLocation(args []interface{}, index int) (*time.Location, error) {
+	if len(args) <= index {
+		return nil, nil
+	}
+	zone := strings.TrimSpace(toolbox.AsString(args[index]))
+	if zone == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %v", zone)
+	}
+	return location, nil
+}
+
+//Now returns current time from the dao clock, it takes optional IANA time zone, i.e. $Now(Europe/Warsaw)
+func Now(source interface{}, state data.Map) (interface{}, error) {
+	location, err := timeLocation(timeArgs(source), 0)
+	if err != nil {
+		return nil, err
+	}
+	now := stateClock(state).Now()
+	if location != nil {
+		now = now.In(location)
+	}
+	return now, nil
+}
+
+//Time returns time of the time expression, it takes optional IANA time zone, i.e. $Time(now-1d/d, UTC), see timeAt
+func Time(source interface{}, state data.Map) (interface{}, error) {
+	args := timeArgs(source)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("unable to run Time, expected time expression")
+	}
+	location, err := timeLocation(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return timeValue(stateClock(state).Now(), args[0], location)
+}
+
+//FormatTime formats time value or expression with java style layout or epoch unit (epoch, epochMillis, epochMicros, epochNanos),
+//it takes optional IANA time zone and truncate unit (y, M, w, d, h, m, s), java style truncate layout or weekday,
+//i.e. $FormatTime(now-1d/d, 'yyyy-MM-dd', UTC)
+func FormatTime(source interface{}, state data.Map) (interface{}, error) {
+	if !toolbox.IsSlice(source) {
+		return nil, fmt.Errorf("unable to run FormatTime: expected %T, but had: %T", []interface{}{}, source)
+	}
+	args := toolbox.AsSlice(source)
+	if len(args) < 2 {
+		return nil, fmt.Errorf("unable to run FormatTime, expected 2 parameters, but had: %v", len(args))
+	}
+	var format = toolbox.AsString(args[1])
+	var layout = toolbox.DateFormatToLayout(format)
+	location, err := timeLocation(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	value, err := timeValue(stateClock(state).Now(), args[0], location)
+	if err != nil {
+		parsed, parseErr := toolbox.ToTime(args[0], layout)
+		if parseErr != nil {
+			return nil, err
+		}
+		if value = *parsed; location != nil {
+			value = value.In(location)
+		}
+	}
+	if len(args) > 3 {
+		truncate := toolbox.AsString(args[3])
+		if truncate == "weekday" {
+			return value.Weekday(), nil
+		}
+		if truncated, ok := truncateTime(value, truncate); ok {
+			value = truncated
+		} else {
+			truncateLayout := toolbox.DateFormatToLayout(truncate)
+			if truncated, err := time.ParseInLocation(truncateLayout, value.Format(truncateLayout), value.Location()); err == nil {
+				value = truncated
+			}
+		}
+	}
+	if unit, ok := epochUnits[strings.ToLower(format)]; ok {
+		return value.UnixNano() / int64(unit), nil
+	}
+	return value.Format(layout), nil
+}
+
+//ParseTime parses text with java style layout or epoch unit (epoch, epochMillis, epochMicros, epochNanos), it takes optional IANA time zone
+//used for text without zone, UTC by default, i.e. $ParseTime('2024-03-01 10:00', 'yyyy-MM-dd HH:mm', Europe/Warsaw)
+func ParseTime(source interface{}, state data.Map) (interface{}, error) {
+	args := timeArgs(source)
+	if len(args) < 2 {
+		return nil, fmt.Errorf("unable to run ParseTime, expected 2 parameters, but had: %v", len(args))
+	}
+	location, err := timeLocation(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	var format = toolbox.AsString(args[1])
+	if unit, ok := epochUnits[strings.ToLower(format)]; ok {
+		value, err := toolbox.ToInt(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v value: %v", format, args[0])
+		}
+		return time.Unix(0, int64(value)*int64(unit)).In(location), nil
+	}
+	result, err := time.ParseInLocation(toolbox.DateFormatToLayout(format), strings.TrimSpace(toolbox.AsString(args[0])), location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %v with %v: %v", args[0], format, err)
+	}
+	return result, nil
+}
+
+//timeValue returns time of time value or time expression in the location, the location of now is used if location is nil
+func timeValue(now time.Time, value interface{}, location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = now.Location()
+	}
+	switch actual := value.(type) {
+	case time.Time:
+		return actual.In(location), nil
+	case *time.Time:
+		return actual.In(location), nil
+	}
+	return timeAt(now.In(location), toolbox.AsString(value))
+}
+
+//timeAt evaluates time expression: now base followed by optional [+-]N offsets with y, M, w, d, h, m, s or ms unit and optional truncation
+//to the unit start, i.e. now-1d/d is yesterday at midnight, now+1M/M is the first day of the next month, +90m is now plus 90 minutes,
+//RFC3339 timestamp is returned as is, other expressions are evaluated with toolbox.TimeDiff, i.e. yesterday, 2daysAgoInUTC
+func timeAt(now time.Time, expression string) (time.Time, error) {
+	var text = strings.TrimSpace(expression)
+	if text == "" {
+		return now, fmt.Errorf("time expression was empty")
+	}
+	var result = now
+	var offset = 0
+	if !strings.ContainsAny(text[:1], "+-/") {
+		for offset < len(text) && unicode.IsLetter(rune(text[offset])) {
+			offset++
+		}
+		if text[:offset] != "now" {
+			if timestamp, err := time.Parse(time.RFC3339Nano, text); err == nil {
+				return timestamp.In(now.Location()), nil
+			}
+			diff, err := toolbox.TimeDiff(now, text)
+			if err != nil {
+				return now, fmt.Errorf("invalid time expression %v", expression)
+			}
+			return *diff, nil
+		}
+	}
+	for offset < len(text) {
+		operator := text[offset]
+		offset++
+		var start = offset
+		for offset < len(text) && unicode.IsDigit(rune(text[offset])) {
+			offset++
+		}
+		var digits = text[start:offset]
+		start = offset
+		for offset < len(text) && unicode.IsLetter(rune(text[offset])) {
+			offset++
+		}
+		unit := text[start:offset]
+		switch operator {
+		case '+', '-':
+			value := toolbox.AsInt(digits)
+			if digits == "" {
+				return now, fmt.Errorf("invalid time expression %v, expected number at %v", expression, start)
+			}
+			if operator == '-' {
+				value = -value
+			}
+			shifted, ok := shiftTime(result, value, unit)
+			if !ok {
+				return now, fmt.Errorf("invalid time expression %v, unsupported unit %q", expression, unit)
+			}
+			result = shifted
+		case '/':
+			truncated, ok := truncateTime(result, unit)
+			if digits != "" || !ok {
+				return now, fmt.Errorf("invalid time expression %v, unsupported truncate unit %q", expression, digits+unit)
+			}
+			result = truncated
+		default:
+			return now, fmt.Errorf("invalid time expression %v, unexpected %q at %v", expression, operator, offset-1)
+		}
+	}
+	return result, nil
+}
+
+//shiftTime adds value of the unit to the time, calendar units (y, M, w, d) keep wall clock time, y and M clamp the day to the target month
+func shiftTime(value time.Time, amount int, unit string) (time.Time, bool) {
+	switch unit {
+	case "y":
+		return addMonths(value, 12*amount), true
+	case "M":
+		return addMonths(value, amount), true
+	case "w":
+		return value.AddDate(0, 0, 7*amount), true
+	case "d":
+		return value.AddDate(0, 0, amount), true
+	case "h":
+		return value.Add(time.Duration(amount) * time.Hour), true
+	case "m":
+		return value.Add(time.Duration(amount) * time.Minute), true
+	case "s":
+		return value.Add(time.Duration(amount) * time.Second), true
+	case "ms":
+		return value.Add(time.Duration(amount) * time.Millisecond), true
+	}
+	return value, false
+}
+
+//addMonths adds months to the time, day is clamped to the last day of the target month, i.e. Jan 31 + 1M is Feb 29 in a leap year
+func addMonths(value time.Time, months int) time.Time {
+	year, month, day := value.Date()
+	target := time.Date(year, month+time.Month(months), 1, value.Hour(), value.Minute(), value.Second(), value.Nanosecond(), value.Location())
+	if lastDay := target.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+	return target.AddDate(0, 0, day-1)
+}
+
+//truncateTime truncates time to the start of the unit in the time location, week starts on Monday
+func truncateTime(value time.Time, unit string) (time.Time, bool) {
+	year, month, day := value.Date()
+	location := value.Location()
+	switch unit {
+	case "y":
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, location), true
+	case "M":
+		return time.Date(year, month, 1, 0, 0, 0, 0, location), true
+	case "w":
+		return time.Date(year, month, day-(int(value.Weekday())+6)%7, 0, 0, 0, 0, location), true
+	case "d":
+		return time.Date(year, month, day, 0, 0, 0, 0, location), true
+	case "h":
+		return time.Date(year, month, day, value.Hour(), 0, 0, 0, location), true
+	case "m":
+		return time.Date(year, month, day, value.Hour(), value.Minute(), 0, 0, location), true
+	case "s":
+		return time.Date(year, month, day, value.Hour(), value.Minute(), value.Second(), 0, location), true
+	}
+	return value, false
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/viant/toolbox/data/udf"
 	"github.com/viant/toolbox/storage"
 	"github.com/viant/toolbox/url"
-	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -21,15 +20,9 @@ import (
 	"time"
 )
 
-//Md5 computes source md5
+//Md5 computes source md5 hex digest, []byte source is hashed as is
 func Md5(source interface{}, state data.Map) (interface{}, error) {
-	hash := md5.New()
-	_, err := io.WriteString(hash, toolbox.AsString(source))
-	if err != nil {
-		return nil, err
-	}
-	var result = fmt.Sprintf("%x", hash.Sum(nil))
-	return result, nil
+	return hashHex(md5.New, source)
 }
 
 //GetOwnerDirectory returns owner neatly document directory
@@ -313,15 +306,57 @@ func IsJSON(fileName interface{}, state data.Map) (interface{}, error) {
 // No parameters
 // Returns the numeric current hour [0,23]
 func CurrentHour(none interface{}, state data.Map) (interface{}, error) {
-	return time.Now().Hour(), nil
+	return stateClock(state).Now().Hour(), nil
+}
+
+//Elapsed returns time elapsed since RFC3339 timestamp, i.e. 2d, 3h, 15s, now is taken from the dao clock
+func Elapsed(source interface{}, state data.Map) (interface{}, error) {
+	inThePast, err := toolbox.ToTime(source, time.RFC3339)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := stateClock(state).Now().Sub(*inThePast).Truncate(time.Second)
+	days := int(elapsed / (24 * time.Hour))
+	hours := int(elapsed.Hours()) % 24
+	minutes := int(elapsed.Minutes()) % 60
+	seconds := int(elapsed.Seconds()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%ds", days, seconds), nil
+	case hours > 0:
+		return fmt.Sprintf("%dh%ds", hours, seconds), nil
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds), nil
+	}
+	return fmt.Sprintf("%ds", seconds), nil
+}
+
+//Rand returns random float in [0, 1) range or random int in [min, max) range, random numbers are taken from the dao random source
+func Rand(source interface{}, state data.Map) (interface{}, error) {
+	floatValue := stateRandom(state).Float64()
+	if source == nil || !toolbox.IsSlice(source) {
+		return floatValue, nil
+	}
+	args := toolbox.AsSlice(source)
+	if len(args) != 2 {
+		return floatValue, nil
+	}
+	min, max := toolbox.AsInt(args[0]), toolbox.AsInt(args[1])
+	return min + int(float64(max-min)*floatValue), nil
 }
 
 const pathKey = "path"
 const valueKey = "value"
 
-// valueAndPath expects a map with keys "path" being the path to the file which contains the rows to match and "value" containing the value to match
+// valueAndPath expects a map with keys "path" being the path to the file which contains the rows to match and "value" containing the value to match,
+// or path and value arguments i.e. $MatchAnyRow(rows.txt, $value)
 // Return value is a boolean.  True if the compare value matches any row in the file.  False if there is an error or no match is found
 func MatchAnyRow(valueAndPath interface{}, state data.Map) (interface{}, error) {
+	if toolbox.IsSlice(valueAndPath) {
+		if args := toolbox.AsSlice(valueAndPath); len(args) == 2 {
+			valueAndPath = map[string]interface{}{pathKey: args[0], valueKey: args[1]}
+		}
+	}
 	if toolbox.IsMap(valueAndPath) { //URL, credentials params case
 		argumentsMap := toolbox.AsMap(valueAndPath)
 		if validateMatchAnyRowKeys(argumentsMap) {
@@ -360,20 +395,5 @@ func validateMatchAnyRowKeys(argumentsMap map[string]interface{}) bool {
 //AddStandardUdf register building udf to the context
 func AddStandardUdf(aMap data.Map) {
 	udf.Register(aMap)
-	aMap.Put("IsJSON", IsJSON)
-	aMap.Put("WorkingDirectory", WorkingDirectory)
-	aMap.Put("Pwd", WorkingDirectory)
-	aMap.Put("HasResource", HasResource)
-	aMap.Put("Md5", Md5)
-	aMap.Put("LoadNeatly", LoadNeatly)
-	aMap.Put("Zip", Zip)
-	aMap.Put("Unzip", Unzip)
-	aMap.Put("UnzipText", UnzipText)
-	aMap.Put("Markdown", Markdown)
-	aMap.Put("Cat", Cat)
-	aMap.Put("LoadBinary", LoadBinary)
-	aMap.Put("AssetsToMap", AssetsToMap)
-	aMap.Put("BinaryAssetsToMap", BinaryAssetsToMap)
-	aMap.Put("CurrentHour", CurrentHour)
-	aMap.Put("MatchAnyRow", MatchAnyRow)
+	standardUdfs.Apply(aMap)
 }
@@ -0,0 +1,282 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox"
+	"github.com/viant/toolbox/data"
+	"strings"
+	"unicode"
+)
+
+//UdfSignature represents udf declaration, optional argument name ends with ?, variadic last argument name ends with ...
+type UdfSignature struct {
+	Name        string
+	Description string
+	Args        []string
+	Returns     string //return type, i.e. string, bool, map
+	Category    string //capability category, i.e. pure, resource, clock
+}
+
+//Arity returns min and max number of arguments, max is -1 for variadic udf
+func (s *UdfSignature) Arity() (int, int) {
+	var min, max = 0, len(s.Args)
+	for _, arg := range s.Args {
+		if strings.HasSuffix(arg, "...") {
+			max = -1
+			continue
+		}
+		if !strings.HasSuffix(arg, "?") {
+			min++
+		}
+	}
+	return min, max
+}
+
+//Validate checks number of call arguments
+func (s *UdfSignature) Validate(args []interface{}) error {
+	min, max := s.Arity()
+	if len(args) >= min && (max == -1 || len(args) <= max) {
+		return nil
+	}
+	var expected = fmt.Sprintf("%v", min)
+	if max == -1 {
+		expected = fmt.Sprintf("at least %v", min)
+	} else if min != max {
+		expected = fmt.Sprintf("%v to %v", min, max)
+	}
+	return fmt.Errorf("%v expects %v arguments (%v), but had %v", s.Name, expected, strings.Join(s.Args, ", "), len(args))
+}
+
+//namedArgs converts map with declared argument names into positional arguments
+func (s *UdfSignature) namedArgs(source interface{}) ([]interface{}, bool) {
+	if source == nil || !toolbox.IsMap(source) {
+		return nil, false
+	}
+	aMap := toolbox.AsMap(source)
+	var result = make([]interface{}, 0)
+	var matched = 0
+	for _, arg := range s.Args {
+		name := strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "?")
+		value, ok := aMap[name]
+		if !ok {
+			break
+		}
+		matched++
+		result = append(result, value)
+	}
+	return result, matched > 0 && matched == len(aMap)
+}
+
+//RegisterUdf registers udf with its signature in the state udf registry, multi argument udf receives call arguments as []interface{}
+func RegisterUdf(state data.Map, signature *UdfSignature, udf func(interface{}, data.Map) (interface{}, error)) {
+	state.Put(signature.Name, udf)
+	udfRegistry(state).Register(signature, udf)
+}
+
+//lookupUdf returns state udf with optional signature
+func lookupUdf(state data.Map, name string) (func(interface{}, data.Map) (interface{}, error), *UdfSignature, bool) {
+	var udf func(interface{}, data.Map) (interface{}, error)
+	switch candidate := state.Get(name).(type) {
+	case func(interface{}, data.Map) (interface{}, error):
+		udf = candidate
+	case data.Udf:
+		udf = candidate
+	default:
+		return nil, nil, false
+	}
+	if registry, ok := state.Get(udfRegistryKey).(*UdfRegistry); ok {
+		if registered, ok := registry.Lookup(name); ok {
+			return udf, registered.UdfSignature, true
+		}
+	}
+	return udf, nil, true
+}
+
+//callUdf calls udf with supplied arguments, udf with signature declaring more than one argument receives arguments as a slice,
+//a single map argument with declared argument names is converted into positional arguments
+func callUdf(state data.Map, name string, args []interface{}) (interface{}, error) {
+	udf, signature, ok := lookupUdf(state, name)
+	if !ok {
+		return nil, fmt.Errorf("undefined function %v", name)
+	}
+	if err := nonDeterministicError(state, name, signature); err != nil {
+		return nil, err
+	}
+	var source interface{}
+	if signature != nil {
+		if len(args) == 1 {
+			if named, ok := signature.namedArgs(args[0]); ok {
+				args = named
+			}
+		}
+		if err := signature.Validate(args); err != nil {
+			return nil, err
+		}
+		if _, max := signature.Arity(); max != 1 {
+			source = args
+		} else if len(args) == 1 {
+			source = args[0]
+		}
+	} else if len(args) == 1 {
+		source = args[0]
+	} else if len(args) > 1 {
+		source = args
+	}
+	result, err := udf(source, state)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	return result, nil
+}
+
+//expandUdfCalls evaluates multi argument $Udf(arg1, arg2) calls and calls of udf with signature requiring more than one argument,
+//remaining calls are expanded with the state, if the text is a single call its value is returned as is
+func (d *Dao) expandUdfCalls(context *tagContext, text string) (interface{}, bool, error) {
+	var result = make([]string, 0)
+	var expanded = false
+	for offset := 0; ; {
+		start, name, open := scanUdfCall(text, offset)
+		if start == -1 {
+			break
+		}
+		udf, signature, known := lookupUdf(context.context, name)
+		if !known && d.validateUdfs && name != templateCallName {
+			if err := nearMissUdfError(context.context, name); err != nil {
+				return nil, false, err
+			}
+		}
+		if err := nonDeterministicError(context.context, name, signature); err != nil {
+			return nil, false, err
+		}
+		end, err := expressionEnd(text, open+1)
+		if err != nil {
+			return nil, false, err
+		}
+		if udf == nil {
+			offset = open + 1
+			continue
+		}
+		arguments := splitArguments(text[open+1 : end])
+		var minArgs = 0
+		if signature != nil {
+			minArgs, _ = signature.Arity()
+		}
+		if len(arguments) < 2 && minArgs < 2 && !isExplicitArgument(arguments) {
+			nested, has, err := d.expandUdfCalls(context, text[open+1:end])
+			if err != nil {
+				return nil, false, err
+			}
+			if has {
+				text = text[:open+1] + toolbox.AsString(nested) + text[end:]
+				expanded = true
+			}
+			offset = open + 1
+			continue
+		}
+		var args = make([]interface{}, len(arguments))
+		var resolved = true
+		for i, argument := range arguments {
+			if args[i], resolved, err = d.udfArgument(context, argument); err != nil {
+				return nil, false, err
+			}
+			if !resolved {
+				break
+			}
+		}
+		if !resolved {
+			offset = end
+			continue
+		}
+		value, err := callUdf(context.context, name, args)
+		if err != nil {
+			return nil, false, err
+		}
+		if start == 0 && end == len(text)-1 && len(result) == 0 {
+			return value, true, nil
+		}
+		result = append(result, text[:start], asText(value))
+		text = text[end+1:]
+		offset = 0
+		expanded = true
+	}
+	result = append(result, text)
+	return strings.Join(result, ""), expanded, nil
+}
+
+//scanUdfCall returns position of the next $Name( call with the name and opening parenthesis position, escaped $$ is skipped
+func scanUdfCall(text string, offset int) (int, string, int) {
+	for i := offset; i < len(text)-1; i++ {
+		if text[i] != '$' {
+			continue
+		}
+		if text[i+1] == '$' {
+			i++
+			continue
+		}
+		var end = i + 1
+		for end < len(text) && (text[end] == '_' || unicode.IsLetter(rune(text[end])) || unicode.IsDigit(rune(text[end]))) {
+			end++
+		}
+		if end > i+1 && end < len(text) && text[end] == '(' {
+			return i, text[i+1 : end], end
+		}
+	}
+	return -1, "", -1
+}
+
+//udfArgument returns call argument value, quoted argument is a text literal, other arguments are normalized cell values,
+//argument referencing undefined variable is not resolved
+func (d *Dao) udfArgument(context *tagContext, argument string) (interface{}, bool, error) {
+	if len(argument) >= 2 && (argument[0] == '\'' || argument[0] == '"') && argument[len(argument)-1] == argument[0] {
+		return unquote(argument), true, nil
+	}
+	value, err := d.normalizeValue(context, argument)
+	if err != nil {
+		return nil, false, err
+	}
+	if explicit, ok := value.(*explicitValue); ok {
+		return explicit.Value(), true, nil
+	}
+	if text, ok := value.(string); ok && strings.Contains(text, "$") {
+		value = context.context.Expand(text)
+		if text, ok := value.(string); ok && hasVariable(text) {
+			return value, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+//isExplicitArgument returns true if the only call argument is explicit value token, i.e. $emptyArray
+func isExplicitArgument(arguments []string) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	_, ok := explicitValues[arguments[0]]
+	return ok
+}
+
+//hasVariable returns true if text contains $variable reference
+func hasVariable(text string) bool {
+	for i := 0; i < len(text)-1; i++ {
+		if text[i] != '$' {
+			continue
+		}
+		if next := rune(text[i+1]); next == '{' || next == '_' || unicode.IsLetter(next) {
+			return true
+		}
+		i++
+	}
+	return false
+}
+
+//asText returns text representation of the value, maps and slices are JSON encoded
+func asText(value interface{}) string {
+	if value != nil && (toolbox.IsMap(value) || toolbox.IsSlice(value)) {
+		if _, ok := value.([]byte); !ok {
+			if text, err := toolbox.AsJSONText(value); err == nil {
+				return strings.TrimSpace(text)
+			}
+		}
+	}
+	return toolbox.AsString(value)
+}
@@ -0,0 +1,220 @@
+package neatly
+
+import (
+	"fmt"
+	"github.com/viant/toolbox/data"
+	"github.com/viant/toolbox/data/udf"
+	"sort"
+	"strings"
+)
+
+//udfRegistryKey represents state key of udf registry
+const udfRegistryKey = "neatlyUdfRegistry"
+
+//Udf capability categories
+const (
+	//UdfPure represents udf that depends only on its arguments and state
+	UdfPure = "pure"
+	//UdfResource represents udf that loads external resources
+	UdfResource = "resource"
+	//UdfEnvironment represents udf that depends on the process environment, i.e. working directory
+	UdfEnvironment = "environment"
+	//UdfClock represents udf that depends on the current time
+	UdfClock = "clock"
+	//UdfRandom represents udf that depends on a random source
+	UdfRandom = "random"
+)
+
+//Udf represents registered udf
+type Udf struct {
+	*UdfSignature
+	Func func(interface{}, data.Map) (interface{}, error)
+}
+
+//UdfRegistry represents udf registry with udf signatures
+type UdfRegistry struct {
+	udfs map[string]*Udf
+}
+
+//Register registers udf with its signature
+func (r *UdfRegistry) Register(signature *UdfSignature, fn func(interface{}, data.Map) (interface{}, error)) {
+	r.udfs[signature.Name] = &Udf{UdfSignature: signature, Func: fn}
+}
+
+//Lookup returns registered udf
+func (r *UdfRegistry) Lookup(name string) (*Udf, bool) {
+	result, ok := r.udfs[name]
+	return result, ok
+}
+
+//Udfs returns registered udfs sorted by category and name
+func (r *UdfRegistry) Udfs() []*Udf {
+	var result = make([]*Udf, 0, len(r.udfs))
+	for _, udf := range r.udfs {
+		result = append(result, udf)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Category != result[j].Category {
+			return result[i].Category < result[j].Category
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+//Apply registers udfs into the state
+func (r *UdfRegistry) Apply(state data.Map) {
+	registry := udfRegistry(state)
+	for name, udf := range r.udfs {
+		state.Put(name, udf.Func)
+		registry.udfs[name] = udf
+	}
+}
+
+//Suggest returns registered udf name closest to the supplied one
+func (r *UdfRegistry) Suggest(name string) (string, bool) {
+	var result = ""
+	var best = len(name)/3 + 1
+	for candidate := range r.udfs {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance <= best && (distance < best || candidate < result) {
+			result, best = candidate, distance
+		}
+	}
+	return result, result != ""
+}
+
+//NewUdfRegistry creates a new udf registry
+func NewUdfRegistry() *UdfRegistry {
+	return &UdfRegistry{udfs: make(map[string]*Udf)}
+}
+
+//udfRegistry returns state udf registry, registry is created if missing
+func udfRegistry(state data.Map) *UdfRegistry {
+	if registry, ok := state.Get(udfRegistryKey).(*UdfRegistry); ok {
+		return registry
+	}
+	var result = NewUdfRegistry()
+	state.Put(udfRegistryKey, result)
+	return result
+}
+
+//nearMissUdfError returns unknown function error if name is a near miss of a registered udf name, i.e. a typo such as LoadNeatyl,
+//otherwise it returns nil as unregistered $Name(...) text stays as is
+func nearMissUdfError(state data.Map, name string) error {
+	suggestion, ok := udfRegistry(state).Suggest(name)
+	if !ok || editDistance(strings.ToLower(name), strings.ToLower(suggestion)) > len(name)/4 {
+		return nil
+	}
+	return fmt.Errorf("unknown function %v, did you mean %v?", name, suggestion)
+}
+
+//editDistance returns Levenshtein distance between texts
+func editDistance(source, target string) int {
+	var previous = make([]int, len(target)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(source); i++ {
+		var current = make([]int, len(target)+1)
+		current[0] = i
+		for j := 1; j <= len(target); j++ {
+			var cost = 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(target)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+//StandardUdfs returns registry of standard udfs, toolbox udfs are included
+func StandardUdfs() *UdfRegistry {
+	var result = NewUdfRegistry()
+	var register = func(name, description, returns, category string, fn func(interface{}, data.Map) (interface{}, error), args ...string) {
+		result.Register(&UdfSignature{Name: name, Description: description, Args: args, Returns: returns, Category: category}, fn)
+	}
+	register("IsJSON", "returns true if resource has JSON content", "bool", UdfResource, IsJSON, "url")
+	register("WorkingDirectory", "returns working directory joined with supplied sub path, '../' is supported", "string", UdfEnvironment, WorkingDirectory, "subPath?")
+	register("Pwd", "alias of WorkingDirectory", "string", UdfEnvironment, WorkingDirectory, "subPath?")
+	register("HasResource", "returns true if external resource exists", "bool", UdfResource, HasResource, "url")
+	register("Md5", "returns md5 hex digest of the value bytes", "string", UdfPure, Md5, "data")
+	register("Sha1", "returns sha1 hex digest of the value bytes", "string", UdfPure, Sha1, "data")
+	register("Sha256", "returns sha256 hex digest of the value bytes", "string", UdfPure, Sha256, "data")
+	register("Sha512", "returns sha512 hex digest of the value bytes", "string", UdfPure, Sha512, "data")
+	register("Hmac", "returns hmac hex digest with md5, sha1, sha256 (default) or sha512", "string", UdfPure, Hmac, "data", "key", "algorithm?")
+	register("Crc32", "returns IEEE crc32 checksum of the value bytes", "int", UdfPure, Crc32, "data")
+	register("Hex", "encodes value bytes as hex text", "string", UdfPure, Hex, "data")
+	register("Base64Encode", "encodes value bytes with standard base64", "string", UdfPure, Base64Encode, "data")
+	register("Base64Decode", "decodes standard, unpadded or URL safe base64 text", "[]byte", UdfPure, Base64Decode, "text")
+	register("UrlEncode", "URL query escapes text", "string", UdfPure, UrlEncode, "text")
+	register("UrlDecode", "URL query unescapes text", "string", UdfPure, UrlDecode, "text")
+	register("JsonEscape", "escapes text to be embedded in JSON string", "string", UdfPure, JsonEscape, "text")
+	register("LoadNeatly", "loads neatly document as data structure", "map", UdfResource, LoadNeatly, "url")
+	register("Zip", "compresses []byte or string", "[]byte", UdfPure, Zip, "data")
+	register("Unzip", "uncompresses []byte", "[]byte", UdfPure, Unzip, "data")
+	register("UnzipText", "uncompresses []byte into string", "string", UdfPure, UnzipText, "data")
+	register("Markdown", "generates HTML for supplied markdown", "string", UdfPure, Markdown, "markdown")
+	register("Cat", "returns resource content", "string", UdfResource, Cat, "url")
+	register("LoadBinary", "returns resource content", "[]byte", UdfResource, LoadBinary, "url")
+	register("AssetsToMap", "loads resources from location into map keyed by relative path", "map", UdfResource, AssetsToMap, "url")
+	register("BinaryAssetsToMap", "loads binary resources from location into map keyed by relative path", "map", UdfResource, BinaryAssetsToMap, "url")
+	register("CurrentHour", "returns the current hour [0,23]", "int", UdfClock, CurrentHour)
+	register("Now", "returns the current time in optional IANA zone", "time", UdfClock, Now, "zone?")
+	register("Time", "returns time of expression, i.e. now-1d/d, now+90m, now+1M/M", "time", UdfClock, Time, "expression", "zone?")
+	register("ParseTime", "parses text with java style layout or epoch unit", "time", UdfPure, ParseTime, "text", "format", "zone?")
+	register("MatchAnyRow", "returns true if value matches any row of the resource", "bool", UdfResource, MatchAnyRow, pathKey, valueKey)
+
+	register("AsInt", "converts value to int", "int", UdfPure, udf.AsInt, "value")
+	register("AsString", "converts value to string", "string", UdfPure, udf.AsString, "value")
+	register("AsFloat", "converts value to float64", "float", UdfPure, udf.AsFloat, "value")
+	register("AsFloat32", "converts value to float32", "float", UdfPure, udf.AsFloat32, "value")
+	register("AsFloat32Ptr", "converts value to *float32", "float", UdfPure, udf.AsFloat32Ptr, "value")
+	register("AsBool", "converts value to bool", "bool", UdfPure, udf.AsBool, "value")
+	register("AsNumber", "converts value to int or float", "number", UdfPure, udf.AsNumber, "value")
+	register("AsMap", "converts JSON or YAML value to map", "map", UdfPure, udf.AsMap, "value")
+	register("AsStringMap", "converts value to map[string]string", "map", UdfPure, udf.AsStringMap, "value")
+	register("AsCollection", "converts JSON or YAML value to slice", "slice", UdfPure, udf.AsCollection, "value")
+	register("AsData", "converts JSON or YAML value to map or slice", "any", UdfPure, udf.AsData, "value")
+	register("AsJSON", "encodes value as JSON", "string", UdfPure, udf.AsJSON, "value")
+	register("AsNewLineDelimitedJSON", "encodes slice as new line delimited JSON", "string", UdfPure, udf.AsNewLineDelimitedJSON, "items")
+	register("Type", "returns value type", "string", UdfPure, udf.Type, "value")
+	register("Length", "returns length of slice, map or string", "int", UdfPure, udf.Length, "value")
+	register("Len", "alias of Length", "int", UdfPure, udf.Length, "value")
+	register("Keys", "returns map keys", "slice", UdfPure, udf.Keys, "map")
+	register("Values", "returns map values", "slice", UdfPure, udf.Values, "map")
+	register("IndexOf", "returns index of item in text or slice, or -1", "int", UdfPure, udf.IndexOf, "collection", "item")
+	register("Join", "joins slice items with separator", "string", UdfPure, udf.Join, "items", "separator")
+	register("Split", "splits text with separator", "slice", UdfPure, udf.Split, "text", "separator")
+	register("Replace", "replaces all old fragments with new", "string", UdfPure, udf.Replace, "text", "old", "new")
+	register("Concat", "concatenates texts or slices", "any", UdfPure, udf.Concat, "items...")
+	register("Merge", "merges maps or map resources", "map", UdfPure, udf.Merge, "maps...")
+	register("ToLower", "converts text to lower case", "string", UdfPure, udf.ToLower, "text")
+	register("ToUpper", "converts text to upper case", "string", UdfPure, udf.ToUpper, "text")
+	register("TrimSpace", "trims leading and trailing spaces", "string", UdfPure, udf.TrimSpace, "text")
+	register("QueryEscape", "URL query escapes text", "string", UdfPure, udf.QueryEscape, "text")
+	register("QueryUnescape", "URL query unescapes text", "string", UdfPure, udf.QueryUnescape, "text")
+	register("Base64DecodeText", "decodes standard base64 text to string", "string", UdfPure, udf.Base64DecodeText, "text")
+	register("Count", "counts state nodes matching path", "int", UdfPure, udf.Count, "path")
+	register("Sum", "sums state nodes values matching path", "number", UdfPure, udf.Sum, "path")
+	register("Select", "selects attributes of state nodes matching path", "slice", UdfPure, udf.Select, "path", "attributes...")
+	register("LoadJSON", "loads JSON or new line delimited JSON resource", "any", UdfResource, udf.LoadJSON, "url")
+	register("FormatTime", "formats time value or expression with java style layout or epoch unit", "string", UdfClock, FormatTime, "time", "format", "zone?", "truncate?")
+	register("Elapsed", "returns time elapsed since RFC3339 timestamp", "string", UdfClock, Elapsed, "time")
+	register("Rand", "returns random float or int in [min, max) range", "number", UdfRandom, Rand, "min?", "max?")
+	return result
+}
+
+var standardUdfs *UdfRegistry
+
+func init() {
+	standardUdfs = StandardUdfs()
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/viant/toolbox/data"
 	"github.com/viant/toolbox/storage"
 	"github.com/viant/toolbox/url"
+	"math/rand"
 	"strings"
 	"testing"
 	"time"
@@ -272,3 +273,282 @@ func Test_MatchToAny(t *testing.T) {
 	matched, _ = neatly.MatchAnyRow(argumentsMap, nil)
 	assert.Equal(t, false, matched)
 }
+
+func Test_MultiArgumentUdf(t *testing.T) {
+	var state = data.NewMap()
+	neatly.RegisterUdf(state, &neatly.UdfSignature{Name: "Pad", Args: []string{"text", "width", "fill?"}}, func(source interface{}, state data.Map) (interface{}, error) {
+		args := toolbox.AsSlice(source)
+		var fill = " "
+		if len(args) > 2 {
+			fill = toolbox.AsString(args[2])
+		}
+		text := toolbox.AsString(args[0])
+		for len(text) < toolbox.AsInt(args[1]) {
+			text += fill
+		}
+		return text, nil
+	})
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(state, url.NewResource("test/use_case33.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "bonono", document["Replaced"])
+	assert.EqualValues(t, true, document["Matched"])
+	assert.EqualValues(t, "x, y", document["Joined"])
+	assert.EqualValues(t, "hello bANANa!", document["Greeting"])
+	assert.EqualValues(t, "banana**", document["Padded"])
+	assert.EqualValues(t, "Banana", document["Computed"])
+	assert.EqualValues(t, false, document["Named"])
+
+	err = dao.Load(state, url.NewResource("test/broken15.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken15.csv:2")
+		assert.Contains(t, err.Error(), "Pad expects 2 to 3 arguments (text, width, fill?), but had 1")
+	}
+}
+
+func TestUdfSignature_Validate(t *testing.T) {
+	var useCases = []struct {
+		args     []string
+		count    int
+		expected string
+	}{
+		{[]string{"text", "old", "new"}, 3, ""},
+		{[]string{"text", "old", "new"}, 2, "Udf expects 3 arguments (text, old, new), but had 2"},
+		{[]string{"items..."}, 0, ""},
+		{[]string{"separator", "items..."}, 0, "Udf expects at least 1 arguments (separator, items...), but had 0"},
+		{[]string{"text", "width?"}, 3, "Udf expects 1 to 2 arguments (text, width?), but had 3"},
+	}
+	for _, useCase := range useCases {
+		signature := &neatly.UdfSignature{Name: "Udf", Args: useCase.args}
+		err := signature.Validate(make([]interface{}, useCase.count))
+		if useCase.expected == "" {
+			assert.Nil(t, err)
+			continue
+		}
+		if assert.NotNil(t, err) {
+			assert.EqualValues(t, useCase.expected, err.Error())
+		}
+	}
+}
+
+func TestUdfRegistry(t *testing.T) {
+	var state = data.NewMap()
+	neatly.AddStandardUdf(state)
+	registry := neatly.StandardUdfs()
+	for _, udf := range registry.Udfs() {
+		assert.NotEmpty(t, udf.Description, udf.Name)
+		assert.NotEmpty(t, udf.Returns, udf.Name)
+		assert.NotEmpty(t, udf.Category, udf.Name)
+		assert.True(t, state.Has(udf.Name), udf.Name)
+	}
+	_, has := registry.Lookup("LoadNeatly")
+	assert.True(t, has)
+	suggestion, _ := registry.Suggest("LoadNeatyl")
+	assert.EqualValues(t, "LoadNeatly", suggestion)
+	_, has = registry.Suggest("Xyz123")
+	assert.False(t, has)
+
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/broken16.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken16.csv:2")
+		assert.Contains(t, err.Error(), "unknown function LoadNeatyl, did you mean LoadNeatly?")
+	}
+	dao.SetUdfValidation(false)
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken16.csv"), &document)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "$LoadNeatyl(x.csv)", document["Doc"])
+	}
+	dao.SetUdfValidation(true)
+	err = dao.Load(data.NewMap(), url.NewResource("test/use_case33.csv"), &document)
+	if assert.Nil(t, err) {
+		assert.Contains(t, document["Padded"], "$Pad(", "Pad is not registered")
+	}
+	err = dao.Load(data.NewMap(), url.NewResource("test/use_case44.csv"), &document)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "echo $Foo(bar) && $Md(5)", document["Command"])
+	}
+}
+
+func TestDao_SetDeterministic(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	dao.SetDeterministic(time.Date(2024, 3, 2, 14, 30, 0, 0, time.UTC), 7)
+	var expected map[string]interface{}
+	for i := 0; i < 2; i++ {
+		var document = make(map[string]interface{})
+		err := dao.Load(data.NewMap(), url.NewResource("test/use_case35.csv"), &document)
+		if !assert.Nil(t, err) {
+			return
+		}
+		assert.EqualValues(t, 14, document["Hour"])
+		assert.EqualValues(t, "2024-03-01 14:30", document["Yesterday"])
+		assert.EqualValues(t, "1d0s", document["Elapsed"])
+		assert.EqualValues(t, time.Saturday, document["Weekday"])
+		if expected == nil {
+			expected = document
+			continue
+		}
+		assert.EqualValues(t, expected, document)
+	}
+
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/broken18.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken18.csv:2")
+		assert.Contains(t, err.Error(), "WorkingDirectory is not deterministic (environment udf)")
+	}
+
+	var state = data.NewMap()
+	state.Put("Custom", func(source interface{}, state data.Map) (interface{}, error) {
+		return source, nil
+	})
+	err = dao.Load(state, url.NewResource("test/broken20.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken20.csv:2")
+		assert.Contains(t, err.Error(), "Custom is not deterministic (udf without signature, register it with RegisterUdf)")
+	}
+
+	var nested = make(map[string]interface{})
+	err = dao.Load(data.NewMap(), url.NewResource("test/use_case38.csv"), &nested)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, expected["Random"], nested["Random"])
+		assert.NotEqual(t, nested["Random"], toolbox.AsMap(nested["Nested"])["Random"])
+	}
+}
+
+func TestDao_SetClock(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	dao.SetClock(neatly.NewFixedClock(time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC)))
+	dao.SetRandomSource(rand.NewSource(1))
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case35.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, 3, document["Hour"])
+	assert.EqualValues(t, "2024-03-01 03:00", document["Yesterday"])
+	assert.EqualValues(t, int(rand.New(rand.NewSource(1)).Float64()*1000000), document["Random"])
+
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken18.csv"), &document)
+	assert.Nil(t, err)
+}
+
+func TestTimeUdfs(t *testing.T) {
+	var now = time.Date(2024, 3, 15, 22, 30, 0, 0, time.UTC)
+	dao := neatly.NewDao(false, "", "", "", nil)
+	dao.SetClock(neatly.NewFixedClock(now))
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case36.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, now, document["Now"])
+	assert.EqualValues(t, time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), document["Yesterday"])
+	assert.EqualValues(t, "2024-03-14 00:00", document["Midnight"])
+	assert.EqualValues(t, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC).UnixNano()/int64(time.Millisecond), document["Later"])
+	assert.EqualValues(t, "2024-04-01 00:00 CEST", document["NextMonth"])
+	assert.EqualValues(t, "2024-03-01 09:00", document["Parsed"])
+	assert.EqualValues(t, "2023-11-14", document["FromEpoch"])
+	assert.EqualValues(t, "2024-03-13", document["Legacy"])
+
+	err = dao.Load(data.NewMap(), url.NewResource("test/broken19.csv"), &document)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "broken19.csv:2")
+		assert.Contains(t, err.Error(), `invalid time expression now-1q, unsupported unit "q"`)
+	}
+
+	monthEnd := neatly.NewDao(false, "", "", "", nil)
+	monthEnd.SetClock(neatly.NewFixedClock(time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)))
+	var monthEndState = data.NewMap()
+	monthEndState.Put(neatly.NeatlyDao, monthEnd)
+	for expression, expected := range map[string]time.Time{
+		"now+1M/M":  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		"now+1M":    time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC),
+		"now+2M":    time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC),
+		"now-2M":    time.Date(2023, 11, 30, 10, 0, 0, 0, time.UTC),
+		"now+1y+1M": time.Date(2025, 2, 28, 10, 0, 0, 0, time.UTC),
+	} {
+		actual, err := neatly.Time(expression, monthEndState)
+		if assert.Nil(t, err, expression) {
+			assert.EqualValues(t, expected, actual, expression)
+		}
+	}
+
+	var useCases = []struct {
+		description string
+		udf         func(interface{}, data.Map) (interface{}, error)
+		args        []interface{}
+		expected    interface{}
+		hasError    bool
+	}{
+		{"timestamp base without offsets", neatly.Time, []interface{}{"2024-03-15T10:00:00Z/w"}, nil, true},
+		{"week truncate", neatly.FormatTime, []interface{}{now, "yyyy-MM-dd", "", "w"}, "2024-03-11", false},
+		{"epoch micros", neatly.FormatTime, []interface{}{"2024-03-15T00:00:00Z", "epochMicros"}, int64(1710460800000000), false},
+		{"epoch millis parse", neatly.ParseTime, []interface{}{1710460800000, "epochMillis"}, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), false},
+		{"unknown zone", neatly.Now, []interface{}{"Mars/Olympus"}, nil, true},
+		{"invalid text", neatly.ParseTime, []interface{}{"15/03/2024", "yyyy-MM-dd"}, nil, true},
+	}
+	for _, useCase := range useCases {
+		actual, err := useCase.udf(useCase.args, nil)
+		if useCase.hasError {
+			assert.NotNil(t, err, useCase.description)
+			continue
+		}
+		if assert.Nil(t, err, useCase.description) {
+			assert.EqualValues(t, useCase.expected, actual, useCase.description)
+		}
+	}
+}
+
+func TestEncodingUdfs(t *testing.T) {
+	dao := neatly.NewDao(false, "", "", "", nil)
+	var document = make(map[string]interface{})
+	err := dao.Load(data.NewMap(), url.NewResource("test/use_case37.csv"), &document)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.EqualValues(t, "176286981cf2b4a00029b00faf110ee676fd7d97669fde9918fe0d596ace0984", document["Digest"])
+	assert.EqualValues(t, "ff00fe6e6561746c7980", document["ZipRoundTrip"])
+	assert.Equal(t, 2031815334, document["Checksum"])
+	assert.EqualValues(t, "9513b4a9c6e75063cb869cb1f4b63ccfaa58269c", document["Signature"])
+	assert.EqualValues(t, "/wD+bmVhdGx5gA==", document["Encoded"])
+	assert.EqualValues(t, "ff00fe6e6561746c7980", document["Decoded"])
+	assert.EqualValues(t, "a+b%26c%3Dd%2F%C3%A9", document["Query"])
+	assert.EqualValues(t, `say \"hi\"<tab>`, document["Escaped"])
+	assert.EqualValues(t, "f77465cc2675f1796ed8278e5b54aaa0d8b9532e", document["Text"])
+
+	var binary = []byte{0xff, 0x00, 0xfe}
+	var useCases = []struct {
+		description string
+		udf         func(interface{}, data.Map) (interface{}, error)
+		source      interface{}
+		expected    interface{}
+		hasError    bool
+	}{
+		{"md5 bytes", neatly.Md5, binary, "13a18f27d9e54107c1d22c7d67f55018", false},
+		{"sha512 text", neatly.Sha512, "abc", "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f", false},
+		{"hex collection bytes", neatly.Hex, []interface{}{byte(0xff), byte(0x01)}, "ff01", false},
+		{"hex collection ints", neatly.Hex, []interface{}{255, 1}, "ff01", false},
+		{"hex collection out of byte range", neatly.Hex, []interface{}{256, 1}, "32353631", false},
+		{"base64 round trip", neatly.Base64Decode, "/wD+", binary, false},
+		{"invalid base64", neatly.Base64Decode, "%%%", nil, true},
+		{"url decode", neatly.UrlDecode, "a+b%26c", "a b&c", false},
+		{"json escape control", neatly.JsonEscape, "a\n\tb", `a\n\tb`, false},
+		{"hmac default sha256", neatly.Hmac, []interface{}{"data", "key"}, "5031fe3d989c6d1537a013fa6e739da23463fdaec3b70137d828e36ace221bd0", false},
+		{"hmac unknown algorithm", neatly.Hmac, []interface{}{"data", "key", "sha3"}, nil, true},
+	}
+	for _, useCase := range useCases {
+		actual, err := useCase.udf(useCase.source, nil)
+		if useCase.hasError {
+			assert.NotNil(t, err, useCase.description)
+			continue
+		}
+		if assert.Nil(t, err, useCase.description) {
+			assert.EqualValues(t, useCase.expected, actual, useCase.description)
+		}
+	}
+}